@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// Espera antes de volver a conectar: se duplica tras cada fallo seguido, hasta el máximo.
+const (
+	reconnectBaseDelay = 500 * time.Millisecond
+	reconnectMaxDelay  = 30 * time.Second
+	// Una sesión que dura al menos esto se considera estable y reinicia la espera.
+	stableSession = 30 * time.Second
+)
+
+// Un vehículo simulado que cruza el puente una y otra vez.
+type car struct {
+	UUID      string
+	Direction string
+	Speed     int
+	// Vehículo generado automáticamente (flota), no manejado por una persona.
+	Synthetic bool
+
+	server string
+	// Transporte usado: TCP (protocolo v2) o HTTP (API REST, como el frontend).
+	transport string
+	stats     *carStats
+	// Muestra cada mensaje del servidor; en la flota solo se muestran los errores.
+	verbose bool
+	// Muestra las estadísticas cada 5 cruces.
+	periodicStats bool
+	// Latencias de la prueba de carga; nil fuera de ella.
+	load *loadRecorder
+	// Archivo donde se guardan el UUID y las estadísticas tras cada cruce; vacío si no se guardan.
+	identityPath string
+	// Token que el servidor entrega al registrar el vehículo (por HTTP o TCP v2); se necesita para volver a registrar el UUID.
+	token string
+}
+
+// Escribe un mensaje con el UUID del vehículo como prefijo.
+func (c *car) logf(format string, args ...interface{}) {
+	fmt.Printf("[%s] "+format+"\n", append([]interface{}{c.UUID}, args...)...)
+}
+
+// Escribe un mensaje solo en modo detallado.
+func (c *car) debugf(format string, args ...interface{}) {
+	if c.verbose {
+		c.logf(format, args...)
+	}
+}
+
+// Error al establecer la conexión con el servidor, antes de que empiece la sesión.
+type connectError struct {
+	err error
+}
+
+func (e *connectError) Error() string { return e.err.Error() }
+
+func (e *connectError) Unwrap() error { return e.err }
+
+// El servidor retiró el vehículo (cancelado, expulsado por un operador o borrado al reiniciar la simulación)
+// o rechazó su UUID por falta de token; no tiene sentido reintentar.
+type removedError struct {
+	reason string
+}
+
+func (e *removedError) Error() string { return e.reason }
+
+// Conecta con el servidor y encadena cruces hasta que se cancela el contexto, reconectando tras cada error.
+func (c *car) run(ctx context.Context) {
+	// Fallos seguidos desde la última sesión estable.
+	failures := 0
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := c.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		var removed *removedError
+		if errors.As(err, &removed) {
+			c.logf("Simulación detenida por el servidor: %s.", removed.reason)
+			return
+		}
+		if time.Since(started) >= stableSession {
+			failures = 0
+		}
+		delay := backoffDelay(failures)
+		failures++
+
+		var connErr *connectError
+		if errors.As(err, &connErr) {
+			c.stats.noteConnectionError()
+			c.logf("Error al conectar: %v. Reintentando en %v...", connErr.err, delay.Round(time.Millisecond))
+		} else {
+			c.stats.noteSessionError()
+			c.logf("Sesión terminada: %v. Reintentando en %v...", err, delay.Round(time.Millisecond))
+		}
+		sleepContext(ctx, delay)
+	}
+}
+
+// Calcula la espera antes del siguiente intento: exponencial con la mitad aleatoria, para que una flota no reconecte a la vez.
+func backoffDelay(failures int) time.Duration {
+	d := reconnectMaxDelay
+	if failures < 16 {
+		d = min(reconnectBaseDelay<<failures, reconnectMaxDelay)
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// Abre una sesión con el transporte del vehículo y la mantiene mientras siga viva.
+func (c *car) session(ctx context.Context) error {
+	if c.transport == transportHTTP {
+		return runHTTPSession(ctx, c)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.server)
+	if err != nil {
+		return &connectError{err}
+	}
+	defer conn.Close()
+
+	// Mantiene la sesión abierta y encadena cruces mientras la conexión siga viva.
+	return runSession(ctx, conn, c)
+}
+
+// Suma un cruce terminado, guarda la identidad si corresponde y muestra las estadísticas cada 5 cruces.
+func (c *car) finishCrossing(tiempoCruce, tiempoEspera time.Duration) {
+	crossings := c.stats.noteCrossing(tiempoCruce, tiempoEspera)
+	c.debugf("Cruce terminado en %v tras esperar %v.", tiempoCruce.Round(time.Millisecond), tiempoEspera.Round(time.Millisecond))
+	c.saveIdentity()
+
+	// Mostrar estadísticas periódicamente
+	if c.periodicStats && crossings%5 == 0 {
+		printStats(c.stats.snapshot(), c.UUID)
+	}
+}
+
+// Guarda el UUID y las estadísticas en el archivo de identidad, si el vehículo tiene uno.
+func (c *car) saveIdentity() {
+	if c.identityPath == "" {
+		return
+	}
+	if err := saveIdentity(c.identityPath, c.UUID, c.token, c.stats.snapshot()); err != nil {
+		c.logf("No se pudo guardar la identidad en %s: %v", c.identityPath, err)
+	}
+}
+
+// Descansa un tiempo aleatorio entre cruces; devuelve false si se canceló el contexto.
+func (c *car) rest(ctx context.Context) bool {
+	tiempoDescanso := rand.Intn(10) + 1
+	c.debugf("Esperando %d segundos antes del próximo intento...\n", tiempoDescanso)
+	return sleepContext(ctx, time.Duration(tiempoDescanso)*time.Second)
+}
+
+// Espera el tiempo indicado o hasta que se cancele el contexto; devuelve false si se canceló.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// Velocidades válidas para el servidor.
+const (
+	minSpeed = 1
+	maxSpeed = 10
+)
+
+// Distribución de la que se sortea la velocidad de cada vehículo.
+type speedDist func(*rand.Rand) int
+
+// Subcomando "fleet": simula muchos vehículos en un solo proceso, cada uno con su UUID y su conexión.
+func runFleet(args []string) {
+	fs := flag.NewFlagSet("fleet", flag.ExitOnError)
+	server := fs.String("server", "", "dirección del servidor (por defecto localhost:8050 en TCP y localhost:8080 en HTTP)")
+	transport := fs.String("transport", transportTCP, "transporte: tcp (protocolo v2) o http (API REST)")
+	n := fs.Int("n", 10, "número de vehículos")
+	north := fs.Float64("north", 0.5, "fracción de vehículos que van hacia el NORTE (0 a 1)")
+	speedSpec := fs.String("speed", "uniform:1-10", "distribución de velocidades: N, uniform:MIN-MAX o normal:MEDIA,DESVIACIÓN")
+	stagger := fs.Duration("stagger", 50*time.Millisecond, "pausa entre el arranque de un vehículo y el siguiente")
+	verbose := fs.Bool("v", false, "muestra los mensajes de cada vehículo")
+	detail := fs.Bool("detail", false, "muestra las estadísticas de cada vehículo al terminar")
+	seed := fs.Int64("seed", 0, "semilla para sortear las velocidades (0 = aleatoria)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Uso: go run . fleet [opciones]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	dist, err := parseSpeedDist(*speedSpec)
+	if err != nil || *n < 1 || *north < 0 || *north > 1 || !validTransport(*transport) {
+		if err != nil {
+			fmt.Fprintln(fs.Output(), err)
+		}
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *server == "" {
+		*server = defaultServer(*transport)
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	cars := buildFleet(*n, *north, dist, rand.New(rand.NewSource(*seed)))
+	for _, c := range cars {
+		c.server = *server
+		c.transport = *transport
+		c.verbose = *verbose
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Iniciando flota de %d vehículos contra %s. Presione Ctrl+C para terminar.\n", len(cars), *server)
+	runCars(ctx, cars, *stagger)
+	printFleetStats(cars, *detail)
+}
+
+// Crea los vehículos de la flota repartiendo las direcciones según la fracción indicada.
+func buildFleet(n int, north float64, dist speedDist, rng *rand.Rand) []*car {
+	prefix := newUUID("Fleet")
+	cars := make([]*car, n)
+	for i := range cars {
+		// Reparto exacto e intercalado: el vehículo i va al norte cuando la cuota acumulada sube.
+		direction := "SUR"
+		if math.Round(float64(i+1)*north) > math.Round(float64(i)*north) {
+			direction = "NORTE"
+		}
+		cars[i] = &car{
+			UUID:      fmt.Sprintf("%s-%03d", prefix, i+1),
+			Direction: direction,
+			Speed:     dist(rng),
+			Synthetic: true,
+			stats:     newStats(),
+		}
+	}
+	return cars
+}
+
+// Arranca cada vehículo en su rutina, con una pausa entre uno y otro, y espera a que todos terminen.
+func runCars(ctx context.Context, cars []*car, stagger time.Duration) {
+	var wg sync.WaitGroup
+	for _, c := range cars {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(c *car) {
+			defer wg.Done()
+			c.run(ctx)
+		}(c)
+		sleepContext(ctx, stagger)
+	}
+	wg.Wait()
+}
+
+// Interpreta la distribución de velocidades: un valor fijo, uniform:MIN-MAX o normal:MEDIA,DESVIACIÓN.
+func parseSpeedDist(spec string) (speedDist, error) {
+	kind, params, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "uniform":
+		lo, hi, ok := strings.Cut(params, "-")
+		minV, err1 := strconv.Atoi(lo)
+		maxV, err2 := strconv.Atoi(hi)
+		if !ok || err1 != nil || err2 != nil || minV > maxV {
+			return nil, fmt.Errorf("distribución uniforme inválida: %q", spec)
+		}
+		return func(rng *rand.Rand) int {
+			return clampSpeed(minV + rng.Intn(maxV-minV+1))
+		}, nil
+	case "normal":
+		m, sd, ok := strings.Cut(params, ",")
+		mean, err1 := strconv.ParseFloat(m, 64)
+		stddev, err2 := strconv.ParseFloat(sd, 64)
+		if !ok || err1 != nil || err2 != nil || stddev < 0 {
+			return nil, fmt.Errorf("distribución normal inválida: %q", spec)
+		}
+		return func(rng *rand.Rand) int {
+			return clampSpeed(int(math.Round(mean + rng.NormFloat64()*stddev)))
+		}, nil
+	default:
+		fixed, err := strconv.Atoi(spec)
+		if err != nil {
+			return nil, fmt.Errorf("distribución de velocidades desconocida: %q", spec)
+		}
+		return func(*rand.Rand) int { return clampSpeed(fixed) }, nil
+	}
+}
+
+// Ajusta la velocidad al rango que acepta el servidor.
+func clampSpeed(v int) int {
+	return min(max(v, minSpeed), maxSpeed)
+}
+
+// Muestra las estadísticas agregadas de la flota y, si se pide, las de cada vehículo.
+func printFleetStats(cars []*car, detail bool) {
+	type dirTotals struct {
+		cars, crossings int
+		wait, cross     time.Duration
+		maxWait         time.Duration
+	}
+	byDir := map[string]*dirTotals{"NORTE": {}, "SUR": {}}
+	var total dirTotals
+	var connErrors, sessionErrors, protocolErrors int
+	var start time.Time
+
+	snapshots := make([]Stats, len(cars))
+	for i, c := range cars {
+		s := c.stats.snapshot()
+		snapshots[i] = s
+		if start.IsZero() || s.StartTime.Before(start) {
+			start = s.StartTime
+		}
+		for _, t := range []*dirTotals{byDir[c.Direction], &total} {
+			t.cars++
+			t.crossings += s.TotalCrossings
+			t.wait += s.TotalWaitingTime
+			t.cross += s.TotalTimeOnBridge
+			t.maxWait = max(t.maxWait, s.MaxWaitingTime)
+		}
+		connErrors += s.ConnectionErrors
+		sessionErrors += s.SessionErrors
+		protocolErrors += s.ProtocolErrors
+	}
+
+	avg := func(d time.Duration, n int) time.Duration {
+		if n == 0 {
+			return 0
+		}
+		return (d / time.Duration(n)).Round(time.Millisecond)
+	}
+
+	elapsed := time.Since(start)
+	fmt.Println("\n=== ESTADÍSTICAS DE LA FLOTA ===")
+	fmt.Printf("Vehículos: %d (NORTE %d, SUR %d)\n", total.cars, byDir["NORTE"].cars, byDir["SUR"].cars)
+	fmt.Printf("Tiempo total de simulación: %v\n", elapsed.Round(time.Second))
+	fmt.Printf("Cruces: %d (%.1f por minuto)\n", total.crossings, float64(total.crossings)/elapsed.Minutes())
+	fmt.Printf("Errores: %d conexiones fallidas, %d sesiones cortadas, %d errores del servidor\n", connErrors, sessionErrors, protocolErrors)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nDirección\tVehículos\tCruces\tEspera prom.\tEspera máx.\tCruce prom.")
+	for _, dir := range []string{"NORTE", "SUR"} {
+		t := byDir[dir]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\t%v\n", dir, t.cars, t.crossings, avg(t.wait, t.crossings), t.maxWait.Round(time.Millisecond), avg(t.cross, t.crossings))
+	}
+	fmt.Fprintf(tw, "Total\t%d\t%d\t%v\t%v\t%v\n", total.cars, total.crossings, avg(total.wait, total.crossings), total.maxWait.Round(time.Millisecond), avg(total.cross, total.crossings))
+	tw.Flush()
+
+	if detail {
+		order := make([]int, len(cars))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return snapshots[order[a]].TotalCrossings > snapshots[order[b]].TotalCrossings })
+
+		fmt.Fprintln(tw, "\nUUID\tDirección\tVelocidad\tCruces\tEspera prom.\tEspera máx.\tErrores")
+		for _, i := range order {
+			c, s := cars[i], snapshots[i]
+			errors := s.ConnectionErrors + s.SessionErrors + s.ProtocolErrors
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%v\t%v\t%d\n", c.UUID, c.Direction, c.Speed, s.TotalCrossings, avg(s.TotalWaitingTime, s.TotalCrossings), s.MaxWaitingTime.Round(time.Millisecond), errors)
+		}
+		tw.Flush()
+	}
+	fmt.Println("================================")
+}
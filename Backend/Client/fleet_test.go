@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParseSpeedDist(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+		// Rango en el que deben caer todas las velocidades generadas.
+		lo, hi int
+	}{
+		{spec: "5", lo: 5, hi: 5},
+		{spec: "0", lo: minSpeed, hi: minSpeed},
+		{spec: "42", lo: maxSpeed, hi: maxSpeed},
+		{spec: "uniform:3-7", lo: 3, hi: 7},
+		{spec: "uniform:4-4", lo: 4, hi: 4},
+		{spec: "uniform:-5-20", wantErr: true},
+		{spec: "uniform:0-20", lo: minSpeed, hi: maxSpeed},
+		{spec: "normal:5,0", lo: 5, hi: 5},
+		{spec: "normal:5,2", lo: minSpeed, hi: maxSpeed},
+		{spec: "normal:50,1", lo: maxSpeed, hi: maxSpeed},
+		{spec: "uniform:7-3", wantErr: true},
+		{spec: "uniform:3", wantErr: true},
+		{spec: "uniform:a-b", wantErr: true},
+		{spec: "normal:5", wantErr: true},
+		{spec: "normal:5,-1", wantErr: true},
+		{spec: "normal:x,1", wantErr: true},
+		{spec: "poisson:3", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			dist, err := parseSpeedDist(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseSpeedDist(%q) no devolvió error", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSpeedDist(%q): %v", tt.spec, err)
+			}
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 1000; i++ {
+				if v := dist(rng); v < tt.lo || v > tt.hi {
+					t.Fatalf("velocidad %d fuera de [%d, %d]", v, tt.lo, tt.hi)
+				}
+			}
+		})
+	}
+}
+
+// Una distribución uniforme debe poder generar los dos extremos de su rango.
+func TestParseSpeedDistUniformCoversRange(t *testing.T) {
+	dist, err := parseSpeedDist("uniform:2-4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[int]bool)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		seen[dist(rng)] = true
+	}
+	for v := 2; v <= 4; v++ {
+		if !seen[v] {
+			t.Errorf("la velocidad %d nunca salió en uniform:2-4", v)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Transportes con los que el cliente puede hablar con el servidor.
+const (
+	transportTCP  = "tcp"
+	transportHTTP = "http"
+)
+
+// Intervalos con los que el cliente HTTP consulta su estado y avisa que sigue vivo, igual que el frontend.
+const (
+	pollInterval      = time.Second
+	heartbeatInterval = 5 * time.Second
+)
+
+// Cliente compartido por todos los vehículos; mantiene conexiones abiertas para no agotar puertos en la flota.
+var httpClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: &http.Transport{MaxIdleConnsPerHost: 100},
+}
+
+// Estado de un vehículo tal como lo devuelve /api/vehicle/{id}.
+type vehicleState struct {
+	ID        int    `json:"id"`
+	Direction string `json:"direction"`
+	Status    string `json:"status"`
+	Stats     struct {
+		TotalCrossings    int
+		TotalTimeOnBridge time.Duration
+		TotalWaitingTime  time.Duration
+		North, South      struct{ Grants int }
+	} `json:"stats"`
+}
+
+// Devuelve los permisos de cruce concedidos al vehículo en ambas direcciones.
+func (v vehicleState) grants() int {
+	return v.Stats.North.Grants + v.Stats.South.Grants
+}
+
+// Error de la API REST: el servidor respondió con un código distinto de 2xx.
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("error del servidor (%d): %s", e.Status, e.Message)
+}
+
+// Devuelve la dirección de servidor por defecto para el transporte indicado.
+func defaultServer(transport string) string {
+	if transport == transportHTTP {
+		return "localhost:8080"
+	}
+	return "localhost:8050"
+}
+
+// Comprueba que el transporte sea uno de los conocidos.
+func validTransport(transport string) bool {
+	return transport == transportTCP || transport == transportHTTP
+}
+
+// Convierte "host:puerto" en la URL base de la API; acepta también una URL completa.
+func apiBase(server string) string {
+	if strings.HasPrefix(server, "http://") || strings.HasPrefix(server, "https://") {
+		return strings.TrimSuffix(server, "/")
+	}
+	return "http://" + server
+}
+
+// Realiza una petición a la API y decodifica la respuesta JSON en out, si no es nil.
+// Si token no está vacío se envía como "Authorization: Bearer".
+func callAPI(ctx context.Context, method, url, token string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return &apiError{Status: resp.StatusCode, Message: e.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Registra el vehículo por la API REST y sigue sus cruces hasta que se cancela el contexto o se pierde la sesión.
+func runHTTPSession(ctx context.Context, c *car) error {
+	base := apiBase(c.server)
+
+	var registered struct {
+		Car   vehicleState `json:"car"`
+		Token string       `json:"token"`
+	}
+	sent := time.Now()
+	// Al volver a registrarse se presenta el token anterior: el servidor lo exige para reutilizar el UUID.
+	err := callAPI(ctx, http.MethodPost, base+"/api/register", c.token, map[string]interface{}{
+		"uuid":      c.UUID,
+		"direction": c.Direction,
+		"speed":     c.Speed,
+		"synthetic": c.Synthetic,
+	}, &registered)
+	c.load.noteRegister()
+	if err != nil {
+		if apiErr, ok := err.(*apiError); ok {
+			c.stats.noteProtocolError()
+			if apiErr.Status == http.StatusUnauthorized {
+				return &removedError{apiErr.Message}
+			}
+			return err
+		}
+		return &connectError{err}
+	}
+	c.load.noteAck(c.Direction, time.Since(sent))
+	if registered.Token != c.token {
+		c.token = registered.Token
+		c.saveIdentity()
+	}
+
+	id := registered.Car.ID
+	vehicleURL := fmt.Sprintf("%s/api/vehicle/%d", base, id)
+	c.debugf("Auto %d registrado hacia el %s.", id, registered.Car.Direction)
+
+	// Al cancelar se pide al servidor que no vuelva a encolar el vehículo, como el botón del frontend.
+	defer func() {
+		if ctx.Err() == nil {
+			return
+		}
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := callAPI(stopCtx, http.MethodPost, vehicleURL+"/stop", c.token, nil, nil); err != nil {
+			c.logf("No se pudo detener el auto %d: %v", id, err)
+		} else {
+			c.debugf("Auto %d detenido; el servidor lo retirará tras su próximo cruce.", id)
+		}
+	}()
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	progress := httpProgress{last: registered.Car, lastCrossing: registered.Car, requestedAt: sent}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-heartbeat.C:
+			if err := callAPI(ctx, http.MethodPost, vehicleURL+"/ping", c.token, nil, nil); err != nil {
+				return c.httpSessionError(ctx, err)
+			}
+		case <-poll.C:
+			var state vehicleState
+			if err := callAPI(ctx, http.MethodGet, vehicleURL, "", nil, &state); err != nil {
+				return c.httpSessionError(ctx, err)
+			}
+			c.noteHTTPState(&progress, state)
+			if state.Status == "cancelled" {
+				return &removedError{"la solicitud de cruce fue cancelada"}
+			}
+		}
+	}
+}
+
+// Cuenta los errores de la API como errores del servidor y devuelve el error que termina la sesión.
+func (c *car) httpSessionError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if _, ok := err.(*apiError); ok {
+		c.stats.noteProtocolError()
+	}
+	return err
+}
+
+// Últimos estados vistos en una sesión HTTP, para detectar cambios entre consultas.
+type httpProgress struct {
+	last         vehicleState
+	lastCrossing vehicleState
+	// Momento en que se pidió el cruce en curso: el envío del registro o, tras un descanso, la consulta que
+	// vio al vehículo de nuevo en cola. El tiempo hasta el permiso se mide desde aquí, como en TCP.
+	requestedAt time.Time
+	// El cruce en curso ya recibió permiso. Una evacuación lo devuelve a la cola sin empezar otro cruce.
+	granted bool
+}
+
+// Compara el estado con la consulta anterior y registra el permiso y el fin de cada cruce.
+func (c *car) noteHTTPState(p *httpProgress, state vehicleState) {
+	prev := p.last
+	p.last = state
+
+	// El fin del cruce se mira primero: una consulta puede ver a la vez la salida del puente y la vuelta a la cola.
+	if state.Stats.TotalCrossings > prev.Stats.TotalCrossings {
+		p.granted = false
+		tiempoCruce := state.Stats.TotalTimeOnBridge - prev.Stats.TotalTimeOnBridge
+		tiempoEspera := state.Stats.TotalWaitingTime - p.lastCrossing.Stats.TotalWaitingTime
+		p.lastCrossing = state
+		c.finishCrossing(tiempoCruce, tiempoEspera)
+	}
+	// El servidor vuelve a encolar el vehículo tras cada descanso; cuenta como un registro más, igual que en TCP.
+	if state.Status == "waiting" && prev.Status != "waiting" {
+		if p.granted {
+			c.debugf("Auto %d evacuado del puente; vuelve al frente de la cola.", state.ID)
+		} else {
+			p.requestedAt = time.Now()
+			c.load.noteRegister()
+			c.debugf("Auto %d en cola hacia el %s.", state.ID, state.Direction)
+		}
+	}
+	if state.grants() > prev.grants() && !p.granted {
+		p.granted = true
+		c.load.noteGrant(prev.Direction, time.Since(p.requestedAt))
+		c.debugf("Auto %d, permiso concedido para cruzar.", state.ID)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Estado del vehículo hacia el norte como lo devolvería /api/vehicle/{id}.
+func polled(status string, grants, crossings int, onBridge, waiting time.Duration) vehicleState {
+	var v vehicleState
+	v.ID, v.Direction, v.Status = 1, "NORTE", status
+	v.Stats.North.Grants = grants
+	v.Stats.TotalCrossings = crossings
+	v.Stats.TotalTimeOnBridge = onBridge
+	v.Stats.TotalWaitingTime = waiting
+	return v
+}
+
+// Sigue las consultas de un vehículo HTTP a lo largo de dos cruces, con una evacuación en el primero.
+func TestNoteHTTPState(t *testing.T) {
+	c := testCar()
+	registered := polled("waiting", 0, 0, 0, 0)
+	p := httpProgress{last: registered, lastCrossing: registered, requestedAt: time.Now().Add(-2 * time.Second)}
+
+	grants := func() []latencySample { return c.load.grants["NORTE"] }
+
+	c.noteHTTPState(&p, polled("crossing", 1, 0, 0, 2*time.Second))
+	if len(grants()) != 1 || grants()[0].latency < 2*time.Second {
+		t.Fatalf("permiso medido desde el registro: %+v", grants())
+	}
+
+	// La evacuación deshace el paso en el servidor y el nuevo permiso es del mismo cruce: no es un registro
+	// ni un permiso más.
+	c.noteHTTPState(&p, polled("waiting", 0, 0, 0, 0))
+	c.noteHTTPState(&p, polled("crossing", 1, 0, 0, 3*time.Second))
+	if c.load.registrations != 0 || len(grants()) != 1 {
+		t.Errorf("tras la evacuación: registros=%d permisos=%d", c.load.registrations, len(grants()))
+	}
+
+	c.noteHTTPState(&p, polled("resting", 1, 1, 4*time.Second, 3*time.Second))
+	if s := c.stats.snapshot(); s.TotalCrossings != 1 || s.TotalTimeOnBridge != 4*time.Second || s.TotalWaitingTime != 3*time.Second {
+		t.Errorf("primer cruce: %+v", s)
+	}
+
+	// Tras el descanso el vehículo vuelve a la cola: el permiso siguiente se mide desde esa consulta.
+	c.noteHTTPState(&p, polled("waiting", 1, 1, 4*time.Second, 3*time.Second))
+	if c.load.registrations != 1 {
+		t.Errorf("registros tras volver a la cola = %d, se esperaba 1", c.load.registrations)
+	}
+	c.noteHTTPState(&p, polled("crossing", 2, 1, 4*time.Second, 3500*time.Millisecond))
+	if len(grants()) != 2 || grants()[1].latency >= time.Second {
+		t.Errorf("segundo permiso: %+v", grants())
+	}
+
+	// Una sola consulta ve la salida del puente y la vuelta a la cola: se cuentan el cruce y el registro.
+	c.noteHTTPState(&p, polled("waiting", 2, 2, 9*time.Second, 3500*time.Millisecond))
+	if s := c.stats.snapshot(); s.TotalCrossings != 2 || s.TotalTimeOnBridge != 9*time.Second || s.TotalWaitingTime != 3500*time.Millisecond {
+		t.Errorf("segundo cruce: %+v", s)
+	}
+	if c.load.registrations != 2 || p.granted {
+		t.Errorf("registros=%d permiso pendiente=%v", c.load.registrations, p.granted)
+	}
+}
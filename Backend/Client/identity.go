@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Contenido del archivo de identidad: el UUID del vehículo y sus estadísticas acumuladas.
+type identity struct {
+	UUID  string `json:"uuid"`
+	Token string `json:"token,omitempty"`
+	Stats Stats  `json:"stats"`
+	// Tiempo de simulación de las ejecuciones anteriores, para que el total siga sumando.
+	ElapsedSec float64   `json:"elapsed_sec"`
+	SavedAt    time.Time `json:"saved_at"`
+}
+
+// Lee el archivo de identidad; devuelve ok=false si todavía no existe.
+func loadIdentity(path string) (id identity, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return id, false, nil
+	}
+	if err != nil {
+		return id, false, err
+	}
+	if err := json.Unmarshal(data, &id); err != nil {
+		return id, false, err
+	}
+	return id, id.UUID != "", nil
+}
+
+// Guarda el UUID, el token y las estadísticas del vehículo; escribe en un archivo temporal y lo renombra para no dejarlo a medias.
+func saveIdentity(path, uuid, token string, stats Stats) error {
+	data, err := json.MarshalIndent(identity{
+		UUID:       uuid,
+		Token:      token,
+		Stats:      stats,
+		ElapsedSec: time.Since(stats.StartTime).Seconds(),
+		SavedAt:    time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Crea las estadísticas de un vehículo a partir de las guardadas, con el tiempo de simulación ya transcurrido.
+func resumeStats(id identity) *carStats {
+	stats := id.Stats
+	stats.StartTime = time.Now().Add(-time.Duration(id.ElapsedSec * float64(time.Second)))
+	return &carStats{stats: stats}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// Número de franjas de concurrencia en las que se agrupan las latencias del informe.
+const loadBands = 10
+
+// Una latencia medida junto con los vehículos activos en ese momento.
+type latencySample struct {
+	latency time.Duration
+	active  int
+}
+
+// Registro de latencias y contadores de una prueba de carga, compartido por todos los vehículos.
+type loadRecorder struct {
+	mu            sync.Mutex
+	active        int
+	registrations int
+	acks          map[string][]latencySample
+	grants        map[string][]latencySample
+}
+
+// Crea un registro vacío.
+func newLoadRecorder() *loadRecorder {
+	return &loadRecorder{
+		acks:   make(map[string][]latencySample),
+		grants: make(map[string][]latencySample),
+	}
+}
+
+// Cuenta un vehículo más en marcha.
+func (r *loadRecorder) carStarted() {
+	r.mu.Lock()
+	r.active++
+	r.mu.Unlock()
+}
+
+// Cuenta una solicitud de registro enviada. No hace nada fuera de una prueba de carga.
+func (r *loadRecorder) noteRegister() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.registrations++
+	r.mu.Unlock()
+}
+
+// Registra el tiempo hasta la primera respuesta al registro. No hace nada fuera de una prueba de carga.
+func (r *loadRecorder) noteAck(direction string, latency time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.acks[direction] = append(r.acks[direction], latencySample{latency, r.active})
+	r.mu.Unlock()
+}
+
+// Registra el tiempo hasta el permiso de cruce. No hace nada fuera de una prueba de carga.
+func (r *loadRecorder) noteGrant(direction string, latency time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.grants[direction] = append(r.grants[direction], latencySample{latency, r.active})
+	r.mu.Unlock()
+}
+
+// Umbrales que la prueba de carga debe cumplir; un valor cero desactiva la comprobación.
+type loadSLO struct {
+	ackP99    time.Duration
+	grantP95  time.Duration
+	errorRate float64
+}
+
+// Subcomando "loadtest": suma vehículos poco a poco y mide cuánto tarda el servidor en responder.
+func runLoadTest(args []string) {
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	server := fs.String("server", "", "dirección del servidor (por defecto localhost:8050 en TCP y localhost:8080 en HTTP)")
+	transport := fs.String("transport", transportTCP, "transporte: tcp (protocolo v2) o http (API REST)")
+	n := fs.Int("cars", 100, "vehículos al final de la rampa")
+	ramp := fs.Duration("ramp", time.Minute, "tiempo en el que se alcanzan todos los vehículos")
+	duration := fs.Duration("duration", 2*time.Minute, "duración total de la prueba, incluida la rampa")
+	north := fs.Float64("north", 0.5, "fracción de vehículos que van hacia el NORTE (0 a 1)")
+	speedSpec := fs.String("speed", "uniform:1-10", "distribución de velocidades: N, uniform:MIN-MAX o normal:MEDIA,DESVIACIÓN")
+	var slo loadSLO
+	fs.DurationVar(&slo.ackP99, "slo-ack-p99", time.Second, "máximo p99 del tiempo hasta la primera respuesta (0 = sin comprobar)")
+	fs.DurationVar(&slo.grantP95, "slo-grant-p95", 0, "máximo p95 del tiempo hasta el permiso de cruce (0 = sin comprobar)")
+	fs.Float64Var(&slo.errorRate, "slo-error-rate", 0.01, "máxima fracción de errores por registro enviado (0 = sin comprobar)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Uso: go run . loadtest [opciones]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	dist, err := parseSpeedDist(*speedSpec)
+	if err != nil || *n < 1 || *north < 0 || *north > 1 || !validTransport(*transport) || *duration <= 0 {
+		if err != nil {
+			fmt.Fprintln(fs.Output(), err)
+		}
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *server == "" {
+		*server = defaultServer(*transport)
+	}
+
+	recorder := newLoadRecorder()
+	cars := buildFleet(*n, *north, dist, rand.New(rand.NewSource(time.Now().UnixNano())))
+	for _, c := range cars {
+		c.server = *server
+		c.transport = *transport
+		c.load = recorder
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	fmt.Printf("Prueba de carga: %d vehículos en %v contra %s, duración %v.\n", *n, *ramp, *server, *duration)
+	start := time.Now()
+	var wg sync.WaitGroup
+	interval := *ramp / time.Duration(*n)
+	for _, c := range cars {
+		if ctx.Err() != nil {
+			break
+		}
+		recorder.carStarted()
+		wg.Add(1)
+		go func(c *car) {
+			defer wg.Done()
+			c.run(ctx)
+		}(c)
+		sleepContext(ctx, interval)
+	}
+	wg.Wait()
+
+	if !printLoadReport(cars, recorder, slo, time.Since(start)) {
+		os.Exit(1)
+	}
+}
+
+// Devuelve el percentil p (0 a 100) de latencias ya ordenadas, por rango más cercano.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// Extrae y ordena las latencias de las muestras.
+func sortedLatencies(samples []latencySample) []time.Duration {
+	latencies := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		latencies[i] = s.latency
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return latencies
+}
+
+// Imprime el informe de la prueba y comprueba los umbrales; devuelve false si alguno se superó.
+func printLoadReport(cars []*car, r *loadRecorder, slo loadSLO, elapsed time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var crossings, connErrors, sessionErrors, protocolErrors int
+	for _, c := range cars {
+		s := c.stats.snapshot()
+		crossings += s.TotalCrossings
+		connErrors += s.ConnectionErrors
+		sessionErrors += s.SessionErrors
+		protocolErrors += s.ProtocolErrors
+	}
+	errors := connErrors + sessionErrors + protocolErrors
+	errorRate := 0.0
+	if r.registrations > 0 {
+		errorRate = float64(errors) / float64(r.registrations)
+	}
+
+	fmt.Println("\n=== PRUEBA DE CARGA ===")
+	fmt.Printf("Duración: %v, vehículos en marcha: %d\n", elapsed.Round(time.Second), r.active)
+	fmt.Printf("Registros enviados: %d, cruces completados: %d (%.1f por minuto)\n", r.registrations, crossings, float64(crossings)/elapsed.Minutes())
+	fmt.Printf("Errores: %d conexiones fallidas, %d sesiones cortadas, %d errores del servidor (%.2f%% de los registros)\n", connErrors, sessionErrors, protocolErrors, errorRate*100)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	var allAcks, allGrants []latencySample
+	for _, table := range []struct {
+		title   string
+		samples map[string][]latencySample
+		all     *[]latencySample
+	}{
+		{"Tiempo hasta la primera respuesta", r.acks, &allAcks},
+		{"Tiempo hasta el permiso de cruce", r.grants, &allGrants},
+	} {
+		fmt.Fprintf(tw, "\n%s\nDirección\tMuestras\tp50\tp90\tp95\tp99\tMáx.\n", table.title)
+		for _, dir := range []string{"NORTE", "SUR", "Total"} {
+			samples := table.samples[dir]
+			if dir == "Total" {
+				samples = append(append([]latencySample(nil), table.samples["NORTE"]...), table.samples["SUR"]...)
+				*table.all = samples
+			}
+			l := sortedLatencies(samples)
+			fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%v\t%v\t%v\n", dir, len(l),
+				roundLatency(percentile(l, 50)), roundLatency(percentile(l, 90)), roundLatency(percentile(l, 95)),
+				roundLatency(percentile(l, 99)), roundLatency(percentile(l, 100)))
+		}
+	}
+	tw.Flush()
+
+	// Las latencias por franja de concurrencia muestran a partir de cuántos vehículos empeora el servidor.
+	bandSize := max((len(cars)+loadBands-1)/loadBands, 1)
+	fmt.Fprintf(tw, "\nPor vehículos activos\nVehículos\tRespuestas\tRespuesta p95\tPermisos\tPermiso p95\n")
+	for lo := 1; lo <= len(cars); lo += bandSize {
+		hi := min(lo+bandSize-1, len(cars))
+		inBand := func(samples []latencySample) []time.Duration {
+			var band []latencySample
+			for _, s := range samples {
+				if s.active >= lo && s.active <= hi {
+					band = append(band, s)
+				}
+			}
+			return sortedLatencies(band)
+		}
+		acks, grants := inBand(allAcks), inBand(allGrants)
+		if len(acks) == 0 && len(grants) == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%d-%d\t%d\t%v\t%d\t%v\n", lo, hi, len(acks), roundLatency(percentile(acks, 95)), len(grants), roundLatency(percentile(grants, 95)))
+	}
+	tw.Flush()
+
+	ok := true
+	check := func(name string, enabled, passed bool, detail string) {
+		if !enabled {
+			return
+		}
+		result := "OK"
+		if !passed {
+			result, ok = "FALLA", false
+		}
+		fmt.Printf("SLO %-28s %-6s %s\n", name, result, detail)
+	}
+	fmt.Println()
+	ackP99 := percentile(sortedLatencies(allAcks), 99)
+	grantP95 := percentile(sortedLatencies(allGrants), 95)
+	check("p99 primera respuesta", slo.ackP99 > 0, ackP99 <= slo.ackP99, fmt.Sprintf("%v (máx. %v)", roundLatency(ackP99), slo.ackP99))
+	check("p95 permiso de cruce", slo.grantP95 > 0, grantP95 <= slo.grantP95, fmt.Sprintf("%v (máx. %v)", roundLatency(grantP95), slo.grantP95))
+	check("tasa de errores", slo.errorRate > 0, errorRate <= slo.errorRate, fmt.Sprintf("%.2f%% (máx. %.2f%%)", errorRate*100, slo.errorRate*100))
+	if len(allAcks) == 0 {
+		fmt.Println("Ningún registro recibió respuesta.")
+		ok = false
+	}
+	return ok
+}
+
+// Redondea una latencia para mostrarla: al microsegundo si es corta, al milisegundo si no.
+func roundLatency(d time.Duration) time.Duration {
+	if d < 10*time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
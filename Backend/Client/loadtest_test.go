@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	// Diez latencias ordenadas: 1s, 2s, ..., 10s.
+	ten := make([]time.Duration, 10)
+	for i := range ten {
+		ten[i] = time.Duration(i+1) * time.Second
+	}
+
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{name: "sin muestras", sorted: nil, p: 50, want: 0},
+		{name: "una muestra", sorted: []time.Duration{3 * time.Second}, p: 99, want: 3 * time.Second},
+		{name: "p0 devuelve el mínimo", sorted: ten, p: 0, want: 1 * time.Second},
+		{name: "p10", sorted: ten, p: 10, want: 1 * time.Second},
+		{name: "p11 sube al siguiente rango", sorted: ten, p: 11, want: 2 * time.Second},
+		{name: "p50", sorted: ten, p: 50, want: 5 * time.Second},
+		{name: "p90", sorted: ten, p: 90, want: 9 * time.Second},
+		{name: "p95", sorted: ten, p: 95, want: 10 * time.Second},
+		{name: "p99", sorted: ten, p: 99, want: 10 * time.Second},
+		{name: "p100 devuelve el máximo", sorted: ten, p: 100, want: 10 * time.Second},
+		{name: "por encima de 100 se limita al máximo", sorted: ten, p: 150, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(p=%g) = %v, se esperaba %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortedLatencies(t *testing.T) {
+	samples := []latencySample{{latency: 3 * time.Second}, {latency: time.Second}, {latency: 2 * time.Second}}
+	got := sortedLatencies(samples)
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sortedLatencies = %v, se esperaba %v", got, want)
+		}
+	}
+	if samples[0].latency != 3*time.Second {
+		t.Error("sortedLatencies modificó las muestras originales")
+	}
+}
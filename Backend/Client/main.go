@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fleet":
+			runFleet(os.Args[2:])
+			return
+		case "loadtest":
+			runLoadTest(os.Args[2:])
+			return
+		}
+	}
+	runSingle(os.Args[1:])
+}
+
+// Simula un solo vehículo con los argumentos originales: servidor, dirección y velocidad.
+func runSingle(args []string) {
+	fs := flag.NewFlagSet("cliente", flag.ExitOnError)
+	transport := fs.String("transport", transportTCP, "transporte: tcp (protocolo v2) o http (API REST)")
+	identityPath := fs.String("identity", "", "archivo donde guardar y reutilizar el UUID y las estadísticas del vehículo")
+	fs.Parse(args)
+	args = fs.Args()
+
+	if len(args) != 3 || !validTransport(*transport) {
+		fmt.Println("Uso: go run . [-transport=tcp|http] [-identity archivo] <servidor:puerto> <direccion> <velocidad>")
+		fmt.Println("     go run . fleet [opciones]   (ver go run . fleet -h)")
+		fmt.Println("     go run . loadtest [opciones]   (ver go run . loadtest -h)")
+		return
+	}
+
+	servidor := args[0]
+	direccion := strings.ToUpper(args[1])
+	velocidad, _ := strconv.Atoi(args[2])
+
+	// Generar un UUID único por cliente
+	c := &car{
+		UUID:          newUUID("Car"),
+		Direction:     direccion,
+		Speed:         velocidad,
+		server:        servidor,
+		transport:     *transport,
+		stats:         newStats(),
+		verbose:       true,
+		periodicStats: true,
+		identityPath:  *identityPath,
+	}
+
+	// Con un archivo de identidad, el vehículo conserva su UUID y sus estadísticas entre ejecuciones.
+	if *identityPath != "" {
+		id, ok, err := loadIdentity(*identityPath)
+		if err != nil {
+			fmt.Printf("No se pudo leer la identidad de %s: %v\n", *identityPath, err)
+			os.Exit(1)
+		}
+		if ok {
+			c.UUID = id.UUID
+			c.token = id.Token
+			c.stats = resumeStats(id)
+			fmt.Printf("Identidad recuperada de %s (%d cruces previos).\n", *identityPath, id.Stats.TotalCrossings)
+		}
+		c.saveIdentity()
+	}
+	fmt.Printf("Iniciando simulación para el vehículo con UUID: %s\n", c.UUID)
+
+	// Se detiene con Ctrl+C y muestra las estadísticas acumuladas.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	c.run(ctx)
+	c.saveIdentity()
+	printStats(c.stats.snapshot(), c.UUID)
+}
+
+// Genera un UUID con el prefijo indicado a partir de la hora actual.
+func newUUID(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Mensaje JSON del protocolo v2, usado tanto para enviar como para recibir.
+type message struct {
+	Type        string  `json:"type"`
+	Version     int     `json:"version,omitempty"`
+	UUID        string  `json:"uuid,omitempty"`
+	ID          int     `json:"id,omitempty"`
+	Direction   string  `json:"direction,omitempty"`
+	Speed       int     `json:"speed,omitempty"`
+	Synthetic   bool    `json:"synthetic,omitempty"`
+	Token       string  `json:"token,omitempty"`
+	Position    int     `json:"position,omitempty"`
+	Percent     float64 `json:"percent,omitempty"`
+	DurationSec float64 `json:"duration_sec,omitempty"`
+	WaitSec     float64 `json:"wait_sec,omitempty"`
+	Code        string  `json:"code,omitempty"`
+	Message     string  `json:"message,omitempty"`
+}
+
+// Negocia el protocolo v2 y realiza cruces sucesivos sobre la misma conexión.
+func runSession(ctx context.Context, conn net.Conn, c *car) error {
+	// Cerrar la conexión desbloquea la lectura en curso cuando se cancela el contexto.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	encoder := json.NewEncoder(conn)
+	scanner := bufio.NewScanner(conn)
+
+	if err := encoder.Encode(message{Type: "hello", Version: 2}); err != nil {
+		return err
+	}
+	reply, err := readMessage(scanner)
+	if err != nil {
+		return err
+	}
+	if reply.Type != "hello" {
+		return fmt.Errorf("respuesta inesperada al saludo: %s %s", reply.Type, reply.Message)
+	}
+
+	for {
+		sent := time.Now()
+		if err := encoder.Encode(message{Type: "register", UUID: c.UUID, Direction: c.Direction, Speed: c.Speed, Synthetic: c.Synthetic, Token: c.token}); err != nil {
+			return err
+		}
+		c.load.noteRegister()
+
+		// Procesa los mensajes del servidor hasta que termina el cruce. Tras una evacuación llega un segundo
+		// permiso para el mismo cruce, que no vuelve a contarse.
+		for finished, acked, granted := false, false, false; !finished; {
+			msg, err := readMessage(scanner)
+			if err != nil {
+				return err
+			}
+
+			// La primera respuesta mide cuánto tarda el servidor en atender el registro.
+			if !acked && (msg.Type == "queued" || msg.Type == "granted") {
+				acked = true
+				c.load.noteAck(c.Direction, time.Since(sent))
+			}
+			if msg.Type == "granted" && !granted {
+				granted = true
+				c.load.noteGrant(c.Direction, time.Since(sent))
+			}
+
+			switch msg.Type {
+			case "registered":
+				// Guarda el token para poder volver a registrar el UUID tras reconectar.
+				if msg.Token != "" && msg.Token != c.token {
+					c.token = msg.Token
+					c.saveIdentity()
+				}
+			case "queued":
+				c.debugf("Auto %d en cola hacia el %s, posición %d.", msg.ID, msg.Direction, msg.Position)
+			case "granted":
+				c.debugf("Auto %d, permiso concedido para cruzar.", msg.ID)
+			case "progress":
+				c.debugf("Cruzando el puente... %.0f%%", msg.Percent)
+			case "revoked":
+				// Tras una evacuación el vehículo vuelve al frente de su cola y espera un nuevo permiso.
+				c.debugf("Auto %d fuera del puente (%s): %s.", msg.ID, msg.Code, msg.Message)
+			case "finished":
+				// Usa los tiempos medidos por el servidor para que las estadísticas coincidan.
+				tiempoCruce := time.Duration(msg.DurationSec * float64(time.Second))
+				tiempoEspera := time.Duration(msg.WaitSec * float64(time.Second))
+				c.finishCrossing(tiempoCruce, tiempoEspera)
+				finished = true
+			case "error":
+				if msg.Code == "cancelled" || msg.Code == "evicted" || msg.Code == "reset" || msg.Code == "unauthorized" {
+					return &removedError{msg.Message}
+				}
+				c.stats.noteProtocolError()
+				return fmt.Errorf("error del servidor (%s): %s", msg.Code, msg.Message)
+			}
+		}
+
+		// Simular tiempo aleatorio antes de volver a intentar
+		if !c.rest(ctx) {
+			return ctx.Err()
+		}
+	}
+}
+
+// Lee y decodifica la siguiente línea JSON enviada por el servidor.
+func readMessage(scanner *bufio.Scanner) (message, error) {
+	var msg message
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return msg, err
+		}
+		return msg, io.EOF
+	}
+	err := json.Unmarshal(scanner.Bytes(), &msg)
+	return msg, err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// Servidor de pega: responde al saludo, espera el registro y envía los mensajes indicados. Cierra done al terminar.
+func scriptedServer(t *testing.T, conn net.Conn, replies []message) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(conn)
+		encoder := json.NewEncoder(conn)
+		for _, want := range []string{"hello", "register"} {
+			msg, err := readMessage(scanner)
+			if err != nil || msg.Type != want {
+				t.Errorf("se esperaba %s y llegó %+v (%v)", want, msg, err)
+				return
+			}
+			if want == "hello" {
+				encoder.Encode(message{Type: "hello", Version: 2})
+			}
+		}
+		for _, reply := range replies {
+			if err := encoder.Encode(reply); err != nil {
+				t.Errorf("enviando %s: %v", reply.Type, err)
+				return
+			}
+		}
+	}()
+	return done
+}
+
+// Coche de prueba hacia el norte que anota sus latencias, sin archivo de identidad.
+func testCar() *car {
+	return &car{UUID: "u1", Direction: "NORTE", Speed: 5, stats: newStats(), load: newLoadRecorder()}
+}
+
+// Una evacuación trae un segundo permiso para el mismo cruce: la prueba de carga cuenta un solo registro y un solo permiso.
+func TestRunSessionCountsOneGrantPerCrossing(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	done := scriptedServer(t, server, []message{
+		{Type: "registered", ID: 1, Token: "t1"},
+		{Type: "queued", ID: 1, Direction: "NORTE", Position: 1},
+		{Type: "granted", ID: 1},
+		{Type: "revoked", ID: 1, Code: "evacuated"},
+		{Type: "queued", ID: 1, Direction: "NORTE", Position: 1},
+		{Type: "granted", ID: 1},
+		{Type: "finished", ID: 1, DurationSec: 2, WaitSec: 1.5},
+	})
+
+	c := testCar()
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- runSession(ctx, client, c) }()
+
+	<-done
+	// El cliente descansa tras el cruce; cancelar lo saca del descanso.
+	deadline := time.Now().Add(2 * time.Second)
+	for c.stats.snapshot().TotalCrossings == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Errorf("runSession = %v, se esperaba context.Canceled", err)
+	}
+
+	stats := c.stats.snapshot()
+	if stats.TotalCrossings != 1 || stats.TotalWaitingTime != 1500*time.Millisecond || stats.TotalTimeOnBridge != 2*time.Second {
+		t.Errorf("estadísticas = %+v", stats)
+	}
+	if c.token != "t1" {
+		t.Errorf("token = %q, se esperaba t1", c.token)
+	}
+	if n, acks, grants := c.load.registrations, len(c.load.acks["NORTE"]), len(c.load.grants["NORTE"]); n != 1 || acks != 1 || grants != 1 {
+		t.Errorf("registros=%d respuestas=%d permisos=%d; se esperaba uno de cada", n, acks, grants)
+	}
+}
+
+// Los códigos con los que el servidor retira al vehículo terminan la sesión sin reintentar; los demás son errores.
+func TestRunSessionStopsWhenRemoved(t *testing.T) {
+	for code, removed := range map[string]bool{"reset": true, "evicted": true, "cancelled": true, "bad_request": false} {
+		client, server := net.Pipe()
+		scriptedServer(t, server, []message{{Type: "error", Code: code, Message: "fuera"}})
+
+		c := testCar()
+		err := runSession(context.Background(), client, c)
+		var removedErr *removedError
+		if errors.As(err, &removedErr) != removed {
+			t.Errorf("código %s: runSession = %v (%T)", code, err, err)
+		}
+		if got := c.stats.snapshot().ProtocolErrors; (got == 0) != removed {
+			t.Errorf("código %s: %d errores de protocolo", code, got)
+		}
+		client.Close()
+		server.Close()
+	}
+}
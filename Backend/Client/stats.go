@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Estadísticas acumuladas de un vehículo.
+type Stats struct {
+	TotalCrossings    int           `json:"total_crossings"`
+	TotalTimeOnBridge time.Duration `json:"total_time_on_bridge_ns"`
+	TotalWaitingTime  time.Duration `json:"total_waiting_time_ns"`
+	MaxWaitingTime    time.Duration `json:"max_waiting_time_ns"`
+	StartTime         time.Time     `json:"-"`
+	// Conexiones fallidas, sesiones cortadas y mensajes de error del servidor.
+	ConnectionErrors int `json:"connection_errors"`
+	SessionErrors    int `json:"session_errors"`
+	ProtocolErrors   int `json:"protocol_errors"`
+}
+
+// Estadísticas de un vehículo compartidas entre su rutina y quien las lee al terminar.
+type carStats struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+// Crea estadísticas vacías que empiezan a contar ahora.
+func newStats() *carStats {
+	return &carStats{stats: Stats{StartTime: time.Now()}}
+}
+
+// Suma un cruce terminado y devuelve el total de cruces.
+func (s *carStats) noteCrossing(tiempoCruce, tiempoEspera time.Duration) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.TotalCrossings++
+	s.stats.TotalTimeOnBridge += tiempoCruce
+	s.stats.TotalWaitingTime += tiempoEspera
+	s.stats.MaxWaitingTime = max(s.stats.MaxWaitingTime, tiempoEspera)
+	return s.stats.TotalCrossings
+}
+
+// Cuenta un intento de conexión fallido.
+func (s *carStats) noteConnectionError() {
+	s.mu.Lock()
+	s.stats.ConnectionErrors++
+	s.mu.Unlock()
+}
+
+// Cuenta una sesión que terminó por un error.
+func (s *carStats) noteSessionError() {
+	s.mu.Lock()
+	s.stats.SessionErrors++
+	s.mu.Unlock()
+}
+
+// Cuenta un mensaje de error recibido del servidor.
+func (s *carStats) noteProtocolError() {
+	s.mu.Lock()
+	s.stats.ProtocolErrors++
+	s.mu.Unlock()
+}
+
+// Devuelve una copia de las estadísticas.
+func (s *carStats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Muestra las estadísticas acumuladas de un vehículo.
+func printStats(stats Stats, uuid string) {
+	totalDuration := time.Since(stats.StartTime)
+	avgCrossingTime := time.Duration(0)
+	avgWaitingTime := time.Duration(0)
+
+	if stats.TotalCrossings > 0 {
+		avgCrossingTime = stats.TotalTimeOnBridge / time.Duration(stats.TotalCrossings)
+		avgWaitingTime = stats.TotalWaitingTime / time.Duration(stats.TotalCrossings)
+	}
+
+	fmt.Println("\n=== ESTADÍSTICAS DEL VEHÍCULO ===")
+	fmt.Printf("UUID: %s\n", uuid)
+	fmt.Printf("Tiempo total de simulación: %v\n", totalDuration.Round(time.Second))
+	fmt.Printf("Número total de cruces: %d\n", stats.TotalCrossings)
+	fmt.Printf("Tiempo total en el puente: %v\n", stats.TotalTimeOnBridge.Round(time.Second))
+	fmt.Printf("Tiempo promedio por cruce: %v\n", avgCrossingTime.Round(time.Second))
+	fmt.Printf("Tiempo total de espera: %v\n", stats.TotalWaitingTime.Round(time.Second))
+	fmt.Printf("Tiempo promedio de espera: %v\n", avgWaitingTime.Round(time.Second))
+	fmt.Printf("Porcentaje de tiempo en puente: %.1f%%\n",
+		float64(stats.TotalTimeOnBridge)/float64(totalDuration)*100)
+	fmt.Println("===============================")
+	fmt.Println()
+}
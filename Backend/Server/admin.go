@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Entradas de la auditoría que se conservan en memoria y se devuelven en /api/admin/audit.
+const maxAuditEntries = 1000
+
+// Tamaño de página por defecto y máximo de la auditoría.
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+// Tipo de la entrada de la auditoría asociada a una petición.
+type AuditEntry = api.AuditEntry
+
+// Variables de la administración.
+var (
+	// Clave de administración; vacía si la API de administración está desactivada.
+	adminKey string
+	// Archivo donde se añaden las entradas de la auditoría (JSON por líneas); vacío para no guardarlas.
+	auditFile string
+	// Entradas pendientes de añadir al archivo; nil si no se guarda. Protegido por el mutex global.
+	auditQueue *writeQueue[AuditEntry]
+	// Se cierra cuando la rutina de escritura terminó de vaciar la cola.
+	auditDone chan struct{}
+	// Últimas entradas de la auditoría, de la más antigua a la más reciente. Protegidas por el mutex global.
+	auditLog []AuditEntry
+	// Número de secuencia de la última entrada. Protegido por el mutex global.
+	auditSeq uint64
+)
+
+// Clave del contexto bajo la que viaja la entrada de la auditoría de la petición.
+type auditContextKey struct{}
+
+// Volcado del estado para /api/admin/state: la instantánea completa sin los tokens, más lo que no se guarda en disco.
+type stateDump struct {
+	stateRecord
+	TCPSessions []int `json:"tcp_sessions"`
+	LeaseCarID  int   `json:"lease_car_id,omitempty"`
+}
+
+// Carga las últimas entradas de la auditoría y abre su archivo para seguir añadiendo.
+func startAuditLog() {
+	if auditFile == "" {
+		return
+	}
+
+	entries, err := readAuditLog(auditFile)
+	if err != nil {
+		log.Printf("[Auditoría] No se pudo leer %s: %v. La auditoría solo se guardará en memoria.", auditFile, err)
+		return
+	}
+	file, err := os.OpenFile(auditFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Printf("[Auditoría] No se pudo abrir %s: %v. La auditoría solo se guardará en memoria.", auditFile, err)
+		return
+	}
+
+	mutex.Lock()
+	auditLog = entries
+	if len(entries) > 0 {
+		auditSeq = entries[len(entries)-1].Seq
+	}
+	auditQueue = newWriteQueue[AuditEntry]()
+	auditDone = make(chan struct{})
+	go auditLoop(file, auditQueue)
+	mutex.Unlock()
+}
+
+// Espera a que se escriban las entradas pendientes y cierra el archivo de la auditoría.
+func stopAuditLog() {
+	mutex.Lock()
+	if auditQueue == nil {
+		mutex.Unlock()
+		return
+	}
+	auditQueue.close()
+	auditQueue = nil
+	mutex.Unlock()
+
+	<-auditDone
+}
+
+// Añade las entradas al archivo de la auditoría, en orden, sin bloquear el mutex global.
+func auditLoop(file *os.File, queue *writeQueue[AuditEntry]) {
+	defer close(auditDone)
+
+	for {
+		entries, ok := queue.take()
+		if !ok {
+			break
+		}
+		for _, entry := range entries {
+			data, _ := json.Marshal(entry)
+			if _, err := file.Write(append(data, '\n')); err != nil {
+				log.Printf("[Auditoría] Error escribiendo la entrada %d: %v", entry.Seq, err)
+			}
+		}
+	}
+	file.Close()
+}
+
+// Lee las últimas maxAuditEntries entradas del archivo de la auditoría; ninguna si no existe.
+func readAuditLog(path string) ([]AuditEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) > maxAuditEntries {
+			entries = entries[1:]
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// Numera la entrada, la guarda en memoria y la encola para el archivo. Debe llamarse con el mutex bloqueado.
+func recordAudit(entry AuditEntry) {
+	auditSeq++
+	entry.Seq = auditSeq
+	entry.Time = time.Now()
+
+	auditLog = append(auditLog, entry)
+	if len(auditLog) > maxAuditEntries {
+		auditLog = auditLog[len(auditLog)-maxAuditEntries:]
+	}
+	if auditQueue != nil {
+		auditQueue.push(entry)
+	}
+}
+
+// Indica si la petición trae la clave de administración.
+func isAdminRequest(r *http.Request) bool {
+	return adminKey != "" && subtle.ConstantTimeCompare([]byte(bearerToken(r)), []byte(adminKey)) == 1
+}
+
+// Crea la entrada de la auditoría de una petición, con la acción y el vehículo afectado si lo hay.
+func newAuditEntry(r *http.Request) AuditEntry {
+	entry := AuditEntry{Action: r.Method + " " + routeTemplate(r), Remote: r.RemoteAddr}
+	// En las demás rutas, como los cierres programados, {id} no es un vehículo.
+	if id, err := strconv.Atoi(mux.Vars(r)["id"]); err == nil && strings.HasPrefix(entry.Action, r.Method+" /api/vehicle/") {
+		entry.VehicleID = id
+	}
+	return entry
+}
+
+// Envuelve un manejador para que solo lo use quien tenga la clave de administración.
+// Los intentos sin clave válida quedan en la auditoría.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminKey == "" {
+			respondWithError(w, http.StatusForbidden, "La API de administración está desactivada. Inicie el servidor con -admin-key")
+			return
+		}
+		if !isAdminRequest(r) {
+			entry := newAuditEntry(r)
+			entry.Status = http.StatusUnauthorized
+			mutex.Lock()
+			recordAudit(entry)
+			mutex.Unlock()
+			respondUnauthorized(w, "Clave de administración inválida o ausente")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Acción de administración: exige la clave y registra la petición y su resultado en la auditoría.
+func adminAction(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(audited(next))
+}
+
+// Ejecuta el manejador y anota en la auditoría la acción, el detalle que añada el manejador y el código de respuesta.
+func audited(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entry := newAuditEntry(r)
+		entry.Authorized = true
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r.WithContext(context.WithValue(r.Context(), auditContextKey{}, &entry)))
+
+		entry.Status = rec.status
+		mutex.Lock()
+		recordAudit(entry)
+		mutex.Unlock()
+	}
+}
+
+// Añade un detalle a la entrada de la auditoría de la petición, si la tiene.
+func auditDetail(r *http.Request, format string, args ...interface{}) {
+	if entry, ok := r.Context().Value(auditContextKey{}).(*AuditEntry); ok {
+		entry.Detail = fmt.Sprintf(format, args...)
+	}
+}
+
+// Manejador HTTP que elimina un coche del sistema en cualquier estado. Si está cruzando, termina su cruce.
+func evictVehicleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de vehículo inválido")
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	car, exists := allCars[id]
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "Vehículo no encontrado")
+		return
+	}
+
+	delete(allCars, id)
+	queueNorth = removeCarFromSlice(queueNorth, id)
+	queueSouth = removeCarFromSlice(queueSouth, id)
+	noteQueueChange(time.Now())
+	disconnectSession(id, "evicted", "El vehículo fue expulsado por un operador")
+	notifyQueuePositions()
+	journalChange(id)
+	removed := carEvent(evRemoved, car)
+	removed.Reason = "evicted"
+	recordEvent(removed)
+
+	message := "Vehículo expulsado."
+	if currentCar != nil && currentCar.ID == id {
+		message = "Vehículo expulsado. Terminará el cruce en curso."
+	}
+	log.Printf("[Auto %d] Expulsado por un operador.", id)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": message})
+}
+
+// Avisa al cliente TCP del coche, si lo tiene, y cierra su sesión tras entregar el aviso. Debe llamarse con el mutex bloqueado.
+func disconnectSession(carID int, code, message string) {
+	session, ok := tcpSessions[carID]
+	if !ok {
+		return
+	}
+	delete(tcpSessions, carID)
+	session.active = false
+	session.sendError(code, message)
+	go func() {
+		session.flush()
+		session.close()
+	}()
+}
+
+// Manejador HTTP que reinicia la simulación: retira a todos los vehículos y pone a cero las estadísticas, los
+// indicadores de equidad, la serie temporal y los cierres programados. El cruce en curso se interrumpe y el
+// puente queda libre. Sobreviven la política de paso, el registro de auditoría, la numeración de vehículos y de
+// cierres, y los contadores del servidor que no dependen de la simulación, como las peticiones HTTP atendidas.
+func resetSimulationHandler(w http.ResponseWriter, r *http.Request) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	now := time.Now()
+	removed := len(allCars)
+	for id := range allCars {
+		disconnectSession(id, "reset", "La simulación fue reiniciada por un administrador")
+	}
+	allCars = make(map[int]Car)
+	queueNorth, queueSouth = nil, nil
+	// carCounter sigue: un cliente que aún guarde su ID no debe confundirse con un vehículo nuevo.
+	clientRegistry = make(map[string]int)
+	vehicleTokens = make(map[int]string)
+	faultyClients = make(map[string]bool)
+	crossingHistory = make(map[int][]CrossingRecord)
+	bridgeClosed = false
+	bridgeClosure = BridgeClosure{}
+	// El contador de cierres programados no se reinicia para no repetir identificadores.
+	closureSchedule = nil
+	// Las esperas, adelantamientos y hambrunas por dirección viven en las estadísticas del puente.
+	bridgeStats = newBridgeTotals(now)
+	timeSeries.reset()
+	// El cruce en curso se interrumpe: se despierta su rutina y el puente queda libre.
+	if evacuationCh != nil {
+		close(evacuationCh)
+		evacuationCh = nil
+	}
+	activeLease = nil
+	bridgeBusy = false
+	currentCar = nil
+	currentDir = ""
+	noteQueueChange(now)
+	recordEvent(bridgeEvent{Type: evSimulationReset})
+	if persistQueue != nil {
+		takeSnapshot()
+	}
+
+	auditDetail(r, "%d vehículos retirados", removed)
+	log.Printf("[Administración] Simulación reiniciada. %d vehículos retirados.", removed)
+	respondWithJSON(w, http.StatusOK, api.MessageResponse{Message: fmt.Sprintf("Simulación reiniciada. Vehículos retirados: %d.", removed)})
+}
+
+// Manejador HTTP que devuelve la política de paso activa y las disponibles.
+func getPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	respondWithJSON(w, http.StatusOK, api.PolicyResponse{Policy: activePolicy, Available: policyNames()})
+}
+
+// Manejador HTTP que cambia la política de paso. Se aplica a partir del próximo coche que reciba paso.
+func setPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	var req api.PolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Formato inválido")
+		return
+	}
+	if _, ok := schedulingPolicies[req.Policy]; !ok {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Política desconocida: %q. Disponibles: %v", req.Policy, policyNames()))
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	previous := activePolicy
+	activePolicy = req.Policy
+	journalChange()
+
+	auditDetail(r, "%s -> %s", previous, req.Policy)
+	log.Printf("[Administración] Política de paso cambiada de %s a %s.", previous, req.Policy)
+	respondWithJSON(w, http.StatusOK, api.PolicyResponse{Policy: activePolicy, Available: policyNames()})
+}
+
+// Manejador HTTP que vuelca el estado completo de la simulación, sin los tokens de los vehículos.
+func dumpStateHandler(w http.ResponseWriter, r *http.Request) {
+	mutex.Lock()
+	dump := stateDump{
+		stateRecord: snapshotRecord(),
+		TCPSessions: make([]int, 0, len(tcpSessions)),
+	}
+	dump.Tokens = nil
+	for id := range tcpSessions {
+		dump.TCPSessions = append(dump.TCPSessions, id)
+	}
+	if activeLease != nil {
+		dump.LeaseCarID = activeLease.carID
+	}
+	mutex.Unlock()
+
+	sort.Ints(dump.TCPSessions)
+	sort.Slice(dump.Cars, func(i, j int) bool { return dump.Cars[i].ID < dump.Cars[j].ID })
+	respondWithJSON(w, http.StatusOK, dump)
+}
+
+// Manejador HTTP que devuelve, paginadas, las entradas de la auditoría; con order=desc, las más recientes primero.
+func getAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	page, pageSize, ok := parsePagination(r, defaultAuditPageSize, maxAuditPageSize)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Parámetros de paginación inválidos")
+		return
+	}
+	desc := false
+	switch r.URL.Query().Get("order") {
+	case "", "asc":
+	case "desc":
+		desc = true
+	default:
+		respondWithError(w, http.StatusBadRequest, "'order' debe ser asc o desc")
+		return
+	}
+
+	mutex.Lock()
+	entries := make([]AuditEntry, len(auditLog))
+	copy(entries, auditLog)
+	mutex.Unlock()
+
+	if desc {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	start := min((page-1)*pageSize, len(entries))
+	end := min(start+pageSize, len(entries))
+
+	respondWithJSON(w, http.StatusOK, api.AuditLogResponse{
+		Total:    len(entries),
+		Page:     page,
+		PageSize: pageSize,
+		Entries:  entries[start:end],
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// El reinicio borra la simulación y lo que depende de ella; la política, la auditoría y la numeración sobreviven.
+func TestResetSimulation(t *testing.T) {
+	freshSimulation(t)
+	prevKey := adminKey
+	adminKey = "k"
+	t.Cleanup(func() { adminKey = prevKey })
+	router := newRouter(false)
+
+	// Con el puente cerrado los coches esperan en cola. El TCP debe recibir el aviso del reinicio.
+	mutex.Lock()
+	bridgeClosed = true
+	mutex.Unlock()
+	c := dialTestClient(t)
+	c.send(clientMessage{Type: msgHello, Version: 2})
+	c.expect(msgHello)
+	c.send(clientMessage{Type: msgRegister, UUID: "reset-tcp", Direction: "SUR", Speed: 5})
+	c.expect(msgRegistered)
+	if w := serveRoute(router, "POST", "/api/register", "", `{"uuid":"reset-http","direction":"NORTE","speed":5}`); w.Code != http.StatusOK {
+		t.Fatalf("registro HTTP: código %d", w.Code)
+	}
+
+	start := time.Now().Add(time.Hour)
+	schedule := `{"start_at":"` + start.Format(time.RFC3339) + `","end_at":"` + start.Add(time.Hour).Format(time.RFC3339) + `"}`
+	for _, req := range []struct{ path, body string }{
+		{"/api/bridge/schedule", schedule},
+		{"/api/admin/policy", `{"policy":"fifo"}`},
+	} {
+		if w := serveRoute(router, "POST", req.path, "k", req.body); w.Code != http.StatusOK {
+			t.Fatalf("POST %s: código %d %s", req.path, w.Code, w.Body)
+		}
+	}
+
+	mutex.Lock()
+	noteBridgeGrant("NORTE", time.Second, time.Now())
+	timeSeries.add(timeSample{Time: time.Now(), QueueNorth: 1})
+	crossingHistory[1] = []CrossingRecord{{CarID: 1, Direction: "NORTE"}}
+	seq := scheduleCounter
+	mutex.Unlock()
+
+	w := serveRoute(router, "POST", "/api/admin/reset", "k", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("reinicio: código %d %s", w.Code, w.Body)
+	}
+	if e := c.expect(msgError); e.Code != "reset" {
+		t.Errorf("aviso al cliente TCP: código %q, se esperaba reset", e.Code)
+	}
+
+	mutex.Lock()
+	if len(allCars) != 0 || len(queueNorth)+len(queueSouth) != 0 || len(clientRegistry) != 0 || len(vehicleTokens) != 0 {
+		t.Errorf("quedan coches=%d colas=%d/%d registro=%d tokens=%d",
+			len(allCars), len(queueNorth), len(queueSouth), len(clientRegistry), len(vehicleTokens))
+	}
+	if len(crossingHistory) != 0 || len(tcpSessions) != 0 || timeSeries.count != 0 {
+		t.Errorf("historial=%d sesiones=%d muestras=%d", len(crossingHistory), len(tcpSessions), timeSeries.count)
+	}
+	if bridgeClosed || bridgeBusy || len(closureSchedule) != 0 || bridgeStats.direction("NORTE").Grants != 0 {
+		t.Errorf("cerrado=%v ocupado=%v programa=%v permisos=%d",
+			bridgeClosed, bridgeBusy, closureSchedule, bridgeStats.direction("NORTE").Grants)
+	}
+	if activePolicy != "fifo" || scheduleCounter != seq {
+		t.Errorf("política=%q contador de cierres=%d; se esperaba fifo y %d", activePolicy, scheduleCounter, seq)
+	}
+	last := auditLog[len(auditLog)-1]
+	mutex.Unlock()
+	if last.Action != "POST /api/admin/reset" || last.Detail != "2 vehículos retirados" {
+		t.Errorf("última entrada de auditoría = %+v", last)
+	}
+
+	// Un UUID anterior se registra de nuevo sin token, con un ID que no repite los de antes del reinicio.
+	w = serveRoute(router, "POST", "/api/register", "", `{"uuid":"reset-http","direction":"NORTE","speed":5}`)
+	var resp api.RegisterResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil || resp.Car.ID != 3 {
+		t.Errorf("registro tras el reinicio: código %d %s", w.Code, w.Body)
+	}
+}
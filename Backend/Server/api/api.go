@@ -0,0 +1,196 @@
+// Package api define los tipos que intercambia la API REST del servidor del puente. Los usan tanto
+// el servidor como el SDK, así que un cambio de campo se refleja en ambos lados al compilar.
+package api
+
+import "time"
+
+// Estructura de un vehículo con sus propiedades y estado, tal como lo devuelve la API.
+type Car struct {
+	ID           int      `json:"id"`
+	UUID         string   `json:"uuid"`
+	Direction    string   `json:"direction"`
+	Speed        int      `json:"speed"`
+	Position     int      `json:"position"`
+	Status       string   `json:"status"`
+	IsLooping    bool     `json:"is_looping"`
+	Stats        CarStats `json:"stats"`
+	CanRequeueAt int64    `json:"can_requeue_at,omitempty"`
+	// En modo arrendamiento el cliente debe avisar su salida antes de que venza el plazo.
+	LeaseMode      bool  `json:"lease_mode,omitempty"`
+	LeaseExpiresAt int64 `json:"lease_expires_at,omitempty"`
+	// Indica que el cliente dejó vencer un arrendamiento sin avisar su salida.
+	Faulty bool `json:"faulty,omitempty"`
+	// Veces que lo adelantó un coche de la otra dirección llegado después, durante la espera actual.
+	OvertakenInQueue int `json:"overtaken_in_queue,omitempty"`
+	// Vehículo generado automáticamente (pestañas extra del frontend, flota del cliente) y no por una persona.
+	Synthetic bool `json:"synthetic"`
+}
+
+// Almacena los datos brutos de las estadísticas de un coche para cálculos internos.
+type CarStats struct {
+	TotalCrossings    int
+	TotalTimeOnBridge time.Duration
+	TotalWaitingTime  time.Duration
+	TimeRegistered    time.Time
+	// Cruces cuyo permiso no pudo entregarse porque el cliente ya no estaba conectado.
+	AbandonedCrossings int
+	// Arrendamientos revocados porque el cliente no avisó su salida a tiempo.
+	RevokedLeases int
+	// Esperas y cruces separados por dirección.
+	North VehicleDirectionTotals
+	South VehicleDirectionTotals
+	// Descansos completos entre un cruce y la vuelta a la cola.
+	Rests         int
+	TotalRestTime time.Duration
+}
+
+// Acumulados de un vehículo en una dirección. La espera se cuenta al recibir paso y la duración
+// del cruce al salir del puente, igual que en las estadísticas globales.
+type VehicleDirectionTotals struct {
+	Grants       int
+	Crossings    int
+	TotalWait    time.Duration
+	MaxWait      time.Duration
+	TotalCrossed time.Duration
+}
+
+// Representa el estado actual y en tiempo real del puente.
+type BridgeStatus struct {
+	Busy           bool   `json:"busy"`
+	CurrentDir     string `json:"current_dir"`
+	CurrentCarID   int    `json:"current_car_id"`
+	QueueNorthSize int    `json:"queue_north_size"`
+	QueueSouthSize int    `json:"queue_south_size"`
+	TrafficLight   string `json:"traffic_light"`
+	// El puente está cerrado: los coches se encolan pero nadie recibe el paso.
+	Closed bool `json:"closed"`
+	// Política que decide qué dirección recibe el paso cuando el puente queda libre.
+	Policy string `json:"policy"`
+	// Motivo y reapertura prevista del cierre; nil con el puente abierto.
+	Closure *BridgeClosure `json:"closure,omitempty"`
+}
+
+// Cierre del puente en curso.
+type BridgeClosure struct {
+	// "drain" deja terminar al coche que cruza; "immediate" lo evacúa al frente de su cola.
+	Mode   string    `json:"mode"`
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since"`
+	// Reapertura automática prevista; nil si el puente queda cerrado hasta /api/bridge/open.
+	ReopenAt *time.Time `json:"reopen_at,omitempty"`
+	// Cierre programado que originó el cierre, si lo hay.
+	ScheduleID int `json:"schedule_id,omitempty"`
+}
+
+// Cuerpo de POST /api/bridge/close. Todos los campos son opcionales; el modo por defecto es "drain".
+type CloseRequest struct {
+	Mode     string     `json:"mode,omitempty"`
+	Reason   string     `json:"reason,omitempty"`
+	ReopenAt *time.Time `json:"reopen_at,omitempty"`
+}
+
+// Cierre programado del puente entre StartAt y EndAt.
+type ScheduledClosure struct {
+	ID      int       `json:"id"`
+	Mode    string    `json:"mode"`
+	Reason  string    `json:"reason,omitempty"`
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at"`
+}
+
+// Cuerpo de POST /api/bridge/schedule. El modo por defecto es "drain".
+type ScheduleClosureRequest struct {
+	Mode    string    `json:"mode,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at"`
+}
+
+// Respuesta de GET /api/bridge/schedule: los cierres programados pendientes, por orden de inicio.
+type ClosureScheduleResponse struct {
+	Closures []ScheduledClosure `json:"closures"`
+}
+
+// Cuerpo de POST /api/register.
+type RegisterRequest struct {
+	UUID      string `json:"uuid"`
+	Direction string `json:"direction"`
+	Speed     int    `json:"speed"`
+	LeaseMode bool   `json:"lease_mode"`
+	Synthetic bool   `json:"synthetic"`
+}
+
+// Respuesta de POST /api/register: el vehículo registrado y el estado del puente en ese momento.
+type RegisterResponse struct {
+	Car          Car          `json:"car"`
+	BridgeStatus BridgeStatus `json:"bridge_status"`
+	// Token secreto del vehículo. Se envía como "Authorization: Bearer <token>" en las acciones
+	// sobre el vehículo y para volver a registrar el mismo UUID.
+	Token string `json:"token"`
+}
+
+// Respuesta de GET /api/queue con ambas colas en orden.
+type QueueResponse struct {
+	North []Car `json:"north"`
+	South []Car `json:"south"`
+}
+
+// Respuesta de las acciones que solo confirman lo hecho.
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// Cuerpo de las respuestas de error de la API.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Evento del puente, tal como se guarda en el registro y se envía por /api/events.
+type Event struct {
+	Seq         uint64    `json:"seq"`
+	Time        time.Time `json:"time"`
+	Type        string    `json:"type"`
+	CarID       int       `json:"car_id,omitempty"`
+	UUID        string    `json:"uuid,omitempty"`
+	Direction   string    `json:"direction,omitempty"`
+	Speed       int       `json:"speed,omitempty"`
+	Transport   string    `json:"transport,omitempty"`
+	Position    int       `json:"position,omitempty"`
+	WaitSec     float64   `json:"wait_sec,omitempty"`
+	DurationSec float64   `json:"duration_sec,omitempty"`
+	RestSec     float64   `json:"rest_sec,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// Cuerpo de POST /api/admin/policy.
+type PolicyRequest struct {
+	Policy string `json:"policy"`
+}
+
+// Respuesta de /api/admin/policy: la política activa y las disponibles.
+type PolicyResponse struct {
+	Policy    string   `json:"policy"`
+	Available []string `json:"available"`
+}
+
+// Una acción de administración registrada en la auditoría.
+type AuditEntry struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// Método y plantilla de la ruta, por ejemplo "POST /api/vehicle/{id}/evict".
+	Action    string `json:"action"`
+	VehicleID int    `json:"vehicle_id,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	Remote    string `json:"remote"`
+	// Falso si la petición no traía una clave de administración válida.
+	Authorized bool `json:"authorized"`
+	Status     int  `json:"status"`
+}
+
+// Respuesta paginada de GET /api/admin/audit.
+type AuditLogResponse struct {
+	Total    int          `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+	Entries  []AuditEntry `json:"entries"`
+}
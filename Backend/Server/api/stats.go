@@ -0,0 +1,225 @@
+package api
+
+import "time"
+
+// Estructura para la respuesta de la API que muestra las estadísticas de un coche.
+type CarStatsResponse struct {
+	TotalCrossings       int     `json:"total_crossings"`
+	TotalTimeOnBridgeSec float64 `json:"total_time_on_bridge_sec"`
+	AvgCrossingTimeSec   float64 `json:"avg_crossing_time_sec"`
+	TotalWaitingTimeSec  float64 `json:"total_waiting_time_sec"`
+	AvgWaitingTimeSec    float64 `json:"avg_waiting_time_sec"`
+	TimeInBridgePercent  float64 `json:"time_in_bridge_percent"`
+	AbandonedCrossings   int     `json:"abandoned_crossings"`
+	RevokedLeases        int     `json:"revoked_leases"`
+
+	// Detalle con las mismas definiciones que las estadísticas globales del puente.
+	MaxWaitingTimeSec float64                          `json:"max_waiting_time_sec"`
+	TotalRestTimeSec  float64                          `json:"total_rest_time_sec"`
+	AvgRestTimeSec    float64                          `json:"avg_rest_time_sec"`
+	Rests             int                              `json:"rests"`
+	TimeBreakdown     TimeBreakdown                    `json:"time_breakdown"`
+	Directions        map[string]VehicleDirectionStats `json:"directions"`
+}
+
+// Estadísticas de un vehículo en una dirección tal como las devuelve la API.
+type VehicleDirectionStats struct {
+	Grants             int     `json:"grants"`
+	Crossings          int     `json:"crossings"`
+	TotalWaitSec       float64 `json:"total_wait_sec"`
+	AvgWaitSec         float64 `json:"avg_wait_sec"`
+	MaxWaitSec         float64 `json:"max_wait_sec"`
+	TotalCrossingSec   float64 `json:"total_crossing_sec"`
+	AvgCrossingTimeSec float64 `json:"avg_crossing_time_sec"`
+}
+
+// Reparto del tiempo de un vehículo desde su registro.
+type TimeBreakdown struct {
+	QueuedSec   float64 `json:"queued_sec"`
+	CrossingSec float64 `json:"crossing_sec"`
+	RestingSec  float64 `json:"resting_sec"`
+	// Tiempo restante: la espera, el cruce o el descanso en curso y los periodos sin actividad.
+	OtherSec        float64 `json:"other_sec"`
+	QueuedPercent   float64 `json:"queued_percent"`
+	CrossingPercent float64 `json:"crossing_percent"`
+	RestingPercent  float64 `json:"resting_percent"`
+	OtherPercent    float64 `json:"other_percent"`
+}
+
+// Estadísticas de una dirección tal como las devuelve la API.
+type DirectionStatsResponse struct {
+	Crossings          int     `json:"crossings"`
+	AvgWaitSec         float64 `json:"avg_wait_sec"`
+	MaxWaitSec         float64 `json:"max_wait_sec"`
+	AvgCrossingTimeSec float64 `json:"avg_crossing_time_sec"`
+	// Distribución de la espera en cola y de la duración del cruce.
+	WaitTime     HistogramResponse `json:"wait_time"`
+	CrossingTime HistogramResponse `json:"crossing_time"`
+}
+
+// Respuesta de la API con las estadísticas globales del puente.
+type BridgeStatsResponse struct {
+	StartTime          time.Time                         `json:"start_time"`
+	UptimeSec          float64                           `json:"uptime_sec"`
+	BusySec            float64                           `json:"busy_sec"`
+	IdleSec            float64                           `json:"idle_sec"`
+	UtilizationPercent float64                           `json:"utilization_percent"`
+	TotalCrossings     int                               `json:"total_crossings"`
+	ThroughputPerMin   float64                           `json:"throughput_per_min"`
+	DirectionSwitches  int                               `json:"direction_switches"`
+	AbandonedCrossings int                               `json:"abandoned_crossings"`
+	RevokedLeases      int                               `json:"revoked_leases"`
+	EvacuatedCrossings int                               `json:"evacuated_crossings"`
+	Directions         map[string]DirectionStatsResponse `json:"directions"`
+	// Distribuciones de ambas direcciones combinadas.
+	WaitTime     HistogramResponse `json:"wait_time"`
+	CrossingTime HistogramResponse `json:"crossing_time"`
+}
+
+// Un intervalo del histograma tal como lo devuelve la API.
+type HistogramBucket struct {
+	// Límite superior del intervalo en segundos, o "+Inf" para el último.
+	UpperBound string `json:"le"`
+	Count      uint64 `json:"count"`
+}
+
+// Resumen de un histograma con sus percentiles y los conteos de cada intervalo.
+type HistogramResponse struct {
+	Count   uint64            `json:"count"`
+	SumSec  float64           `json:"sum_sec"`
+	MaxSec  float64           `json:"max_sec"`
+	P50Sec  float64           `json:"p50_sec"`
+	P90Sec  float64           `json:"p90_sec"`
+	P95Sec  float64           `json:"p95_sec"`
+	P99Sec  float64           `json:"p99_sec"`
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+// Resumen de un vehículo registrado tal como aparece en el listado.
+type VehicleListItem struct {
+	ID           int       `json:"id"`
+	UUID         string    `json:"uuid"`
+	Direction    string    `json:"direction"`
+	Speed        int       `json:"speed"`
+	Status       string    `json:"status"`
+	IsLooping    bool      `json:"is_looping"`
+	Synthetic    bool      `json:"synthetic"`
+	RegisteredAt time.Time `json:"registered_at"`
+	Crossings    int       `json:"crossings"`
+	AvgWaitSec   float64   `json:"avg_wait_sec"`
+	MaxWaitSec   float64   `json:"max_wait_sec"`
+}
+
+// Respuesta paginada del listado de vehículos.
+type VehicleListResponse struct {
+	Total    int               `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+	Vehicles []VehicleListItem `json:"vehicles"`
+}
+
+// Un puesto de la clasificación.
+type LeaderboardEntry struct {
+	Rank    int             `json:"rank"`
+	Vehicle VehicleListItem `json:"vehicle"`
+}
+
+// Respuesta de la API con la clasificación de vehículos.
+type LeaderboardResponse struct {
+	By      string             `json:"by"`
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+// Registro de un cruce individual de un vehículo.
+type CrossingRecord struct {
+	CarID                int       `json:"car_id"`
+	Direction            string    `json:"direction"`
+	QueuedAt             time.Time `json:"queued_at"`
+	GrantedAt            time.Time `json:"granted_at"`
+	ExitedAt             time.Time `json:"exited_at"`
+	WaitSec              float64   `json:"wait_sec"`
+	DurationSec          float64   `json:"duration_sec"`
+	QueueLengthAtArrival int       `json:"queue_length_at_arrival"`
+	// Veces que un coche de la otra dirección llegado después cruzó antes durante esta espera.
+	TimesOvertaken int `json:"times_overtaken"`
+}
+
+// Respuesta paginada del historial de cruces de un vehículo.
+type CrossingHistoryResponse struct {
+	VehicleID int              `json:"vehicle_id"`
+	Total     int              `json:"total"`
+	Page      int              `json:"page"`
+	PageSize  int              `json:"page_size"`
+	Crossings []CrossingRecord `json:"crossings"`
+}
+
+// Un punto de la serie temporal: el resumen de las muestras de un intervalo de tamaño step.
+type TimeSeriesPoint struct {
+	Time          time.Time `json:"time"`
+	Samples       int       `json:"samples"`
+	QueueNorthAvg float64   `json:"queue_north_avg"`
+	QueueNorthMax int       `json:"queue_north_max"`
+	QueueSouthAvg float64   `json:"queue_south_avg"`
+	QueueSouthMax int       `json:"queue_south_max"`
+	// Fracción de las muestras en que el puente estaba ocupado (0 a 1).
+	BusyRatio float64 `json:"busy_ratio"`
+	// Dirección con paso en la última muestra del intervalo.
+	Direction string `json:"direction"`
+}
+
+// Respuesta de la API con la serie temporal de las colas.
+type TimeSeriesResponse struct {
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	StepSec     float64           `json:"step_sec"`
+	IntervalSec float64           `json:"interval_sec"`
+	Points      []TimeSeriesPoint `json:"points"`
+}
+
+// Indicadores de equidad de una dirección.
+type DirectionFairness struct {
+	AvgWaitSec float64 `json:"avg_wait_sec"`
+	// Mayor tiempo que la dirección tuvo coches esperando sin que se diera paso a ninguno.
+	LongestStarvationSec float64 `json:"longest_starvation_sec"`
+	// Tiempo que lleva la dirección esperando sin paso; cero si no tiene coches en cola.
+	CurrentStarvationSec float64 `json:"current_starvation_sec"`
+	// Veces que un coche de esta dirección fue adelantado por uno llegado después a la otra dirección.
+	Overtakes int `json:"overtakes"`
+}
+
+// Indicadores de equidad de un vehículo.
+type VehicleFairness struct {
+	VehicleID      int     `json:"vehicle_id"`
+	Crossings      int     `json:"crossings"`
+	AvgWaitSec     float64 `json:"avg_wait_sec"`
+	TimesOvertaken int     `json:"times_overtaken"`
+}
+
+// Respuesta de la API con los indicadores de equidad del puente.
+type FairnessResponse struct {
+	// Índice de Jain sobre la espera media de cada vehículo: 1 es un reparto perfecto, 1/n el peor.
+	JainIndex float64 `json:"jain_index"`
+	// Vehículos con al menos un cruce, que son los que entran en el índice.
+	VehiclesConsidered int `json:"vehicles_considered"`
+	// Espera media del norte dividida entre la del sur; null mientras el sur no tenga esperas.
+	WaitRatioNorthSouth *float64                     `json:"wait_ratio_north_south"`
+	Directions          map[string]DirectionFairness `json:"directions"`
+	Vehicles            []VehicleFairness            `json:"vehicles"`
+}
+
+// Resumen de un vehículo calculado a partir de sus cruces.
+type VehicleSummary struct {
+	VehicleID int    `json:"vehicle_id"`
+	UUID      string `json:"uuid"`
+	// Estado actual del vehículo, o "removed" si ya fue dado de baja.
+	Status           string     `json:"status"`
+	Crossings        int        `json:"crossings"`
+	TotalWaitSec     float64    `json:"total_wait_sec"`
+	AvgWaitSec       float64    `json:"avg_wait_sec"`
+	MaxWaitSec       float64    `json:"max_wait_sec"`
+	TotalCrossingSec float64    `json:"total_crossing_sec"`
+	AvgCrossingSec   float64    `json:"avg_crossing_sec"`
+	TimesOvertaken   int        `json:"times_overtaken"`
+	FirstCrossingAt  *time.Time `json:"first_crossing_at"`
+	LastCrossingAt   *time.Time `json:"last_crossing_at"`
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Bytes aleatorios de cada token de vehículo; se entrega codificado en hexadecimal.
+const vehicleTokenBytes = 32
+
+// Token secreto de cada vehículo registrado por HTTP o por TCP v2, por ID. Protegido por el mutex global.
+// Se conserva tras la baja del coche para que su dueño siga pudiendo consultar el historial.
+var vehicleTokens = make(map[int]string)
+
+// Devuelve el token del vehículo y, si todavía no tiene uno, lo genera. Debe llamarse con el mutex bloqueado.
+func vehicleToken(id int) (string, error) {
+	if token, ok := vehicleTokens[id]; ok {
+		return token, nil
+	}
+	b := make([]byte, vehicleTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	vehicleTokens[id] = token
+	return token, nil
+}
+
+// Comprueba que token sea el del vehículo. Un vehículo sin token no acepta ninguno.
+// Debe llamarse con el mutex bloqueado.
+func vehicleTokenMatches(id int, token string) bool {
+	expected, ok := vehicleTokens[id]
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// Indica si se puede volver a registrar el UUID del vehículo con el token dado. Un vehículo con token exige
+// ese token. Uno sin token, registrado por TCP v1 que no puede recibirlo, solo se puede reclamar mientras
+// ninguna sesión TCP lo tenga abierto. Debe llamarse con el mutex bloqueado.
+func canClaimVehicle(id int, token string) bool {
+	if _, ok := vehicleTokens[id]; ok {
+		return vehicleTokenMatches(id, token)
+	}
+	_, live := tcpSessions[id]
+	return !live
+}
+
+// Extrae el token del encabezado "Authorization: Bearer <token>"; vacío si no lo hay.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Responde 401 indicando que hace falta el token del vehículo.
+func respondUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="puente"`)
+	respondWithError(w, http.StatusUnauthorized, message)
+}
+
+// Envuelve un manejador de /api/vehicle/{id}/... para que solo lo use quien tenga el token de ese vehículo.
+func requireVehicleToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "ID de vehículo inválido")
+			return
+		}
+		// La administración puede actuar sobre cualquier vehículo; queda en la auditoría.
+		if isAdminRequest(r) {
+			audited(next)(w, r)
+			return
+		}
+		mutex.Lock()
+		// Un ID sin token se rechaza igual que un token incorrecto, para no revelar qué IDs existen.
+		ok := vehicleTokenMatches(id, bearerToken(r))
+		mutex.Unlock()
+
+		if !ok {
+			respondUnauthorized(w, "Token de vehículo inválido o ausente")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+	"github.com/gorilla/mux"
+)
+
+// Deja los tokens y las sesiones TCP indicados durante la prueba y restaura los anteriores al terminar.
+func withTokens(t *testing.T, tokens map[int]string, sessions map[int]*tcpSession) {
+	t.Helper()
+	prevTokens, prevSessions := vehicleTokens, tcpSessions
+	vehicleTokens, tcpSessions = tokens, sessions
+	t.Cleanup(func() {
+		vehicleTokens, tcpSessions = prevTokens, prevSessions
+	})
+}
+
+func TestVehicleTokenMatches(t *testing.T) {
+	withTokens(t, map[int]string{1: "secreto"}, map[int]*tcpSession{})
+
+	tests := []struct {
+		name  string
+		id    int
+		token string
+		want  bool
+	}{
+		{name: "token correcto", id: 1, token: "secreto", want: true},
+		{name: "token incorrecto", id: 1, token: "otro"},
+		{name: "token vacío", id: 1, token: ""},
+		{name: "prefijo del token", id: 1, token: "secre"},
+		{name: "vehículo sin token y token vacío", id: 2, token: ""},
+		{name: "vehículo sin token y token cualquiera", id: 2, token: "secreto"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vehicleTokenMatches(tt.id, tt.token); got != tt.want {
+				t.Errorf("vehicleTokenMatches(%d, %q) = %v, se esperaba %v", tt.id, tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanClaimVehicle(t *testing.T) {
+	withTokens(t, map[int]string{1: "secreto", 3: "tcp"}, map[int]*tcpSession{2: {}, 3: {}})
+
+	tests := []struct {
+		name  string
+		id    int
+		token string
+		want  bool
+	}{
+		{name: "con token correcto", id: 1, token: "secreto", want: true},
+		{name: "con token incorrecto", id: 1, token: "otro"},
+		{name: "con token y sin enviarlo", id: 1, token: ""},
+		{name: "sin token con sesión TCP abierta", id: 2, token: ""},
+		{name: "sin token con sesión TCP abierta y token cualquiera", id: 2, token: "secreto"},
+		{name: "TCP v2 con su token", id: 3, token: "tcp", want: true},
+		{name: "TCP v2 sin su token", id: 3, token: ""},
+		{name: "sin token ni sesión", id: 4, token: "", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canClaimVehicle(tt.id, tt.token); got != tt.want {
+				t.Errorf("canClaimVehicle(%d, %q) = %v, se esperaba %v", tt.id, tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "Bearer abc", want: "abc"},
+		{header: "bearer abc", want: "abc"},
+		{header: "Bearer  abc ", want: "abc"},
+		{header: "Basic abc", want: ""},
+		{header: "Bearer", want: ""},
+		{header: "abc", want: ""},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		if got := bearerToken(r); got != tt.want {
+			t.Errorf("bearerToken(%q) = %q, se esperaba %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRequireVehicleToken(t *testing.T) {
+	withTokens(t, map[int]string{1: "secreto"}, map[int]*tcpSession{})
+
+	tests := []struct {
+		name   string
+		id     string
+		header string
+		status int
+	}{
+		{name: "token correcto", id: "1", header: "Bearer secreto", status: http.StatusOK},
+		{name: "token incorrecto", id: "1", header: "Bearer otro", status: http.StatusUnauthorized},
+		{name: "sin encabezado", id: "1", status: http.StatusUnauthorized},
+		{name: "vehículo sin token", id: "2", header: "Bearer secreto", status: http.StatusUnauthorized},
+		{name: "ID inválido", id: "x", header: "Bearer secreto", status: http.StatusBadRequest},
+	}
+
+	handler := requireVehicleToken(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/vehicle/"+tt.id+"/stop", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": tt.id})
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != tt.status {
+				t.Errorf("código %d, se esperaba %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("falta el encabezado WWW-Authenticate en la respuesta 401")
+			}
+		})
+	}
+}
+
+// Envía una petición al enrutador con el cuerpo y el token indicados, si los hay.
+func serveRoute(h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// Las rutas de un vehículo rechazan a quien no trae su token, incluido el token de otro vehículo.
+func TestRouterRejectsMissingVehicleToken(t *testing.T) {
+	freshSimulation(t)
+	mutex.Lock()
+	bridgeClosed = true
+	mutex.Unlock()
+	router := newRouter(false)
+
+	var tokens [2]string
+	for i, uuid := range []string{"router-a", "router-b"} {
+		rec := serveRoute(router, "POST", "/api/register", "", `{"uuid":"`+uuid+`","direction":"NORTE","speed":5}`)
+		var resp api.RegisterResponse
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil || resp.Car.ID != i+1 {
+			t.Fatalf("registro de %s: %d %s", uuid, rec.Code, rec.Body)
+		}
+		tokens[i] = resp.Token
+	}
+
+	routes := []struct{ method, path string }{
+		{"POST", "/api/vehicle/1/stop"},
+		{"POST", "/api/vehicle/1/ping"},
+		{"POST", "/api/vehicle/1/exit"},
+		{"GET", "/api/vehicle/1/history"},
+		{"POST", "/api/vehicle/1/cancel"},
+	}
+	for _, route := range routes {
+		for _, token := range []string{"", tokens[1]} {
+			if w := serveRoute(router, route.method, route.path, token, ""); w.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s con token %q: código %d, se esperaba 401", route.method, route.path, token, w.Code)
+			}
+		}
+	}
+
+	// Ninguno de los rechazos tocó el coche: sigue en la cola y su token abre las rutas.
+	mutex.Lock()
+	status := allCars[1].Status
+	mutex.Unlock()
+	if status != "waiting" {
+		t.Errorf("estado del coche 1 = %q tras los rechazos", status)
+	}
+	if w := serveRoute(router, "POST", "/api/vehicle/1/ping", tokens[0], ""); w.Code != http.StatusOK {
+		t.Errorf("ping con su token: código %d", w.Code)
+	}
+	if w := serveRoute(router, "GET", "/api/vehicle/1/history", tokens[0], ""); w.Code != http.StatusOK {
+		t.Errorf("historial con su token: código %d", w.Code)
+	}
+
+	// Volver a registrar un UUID ajeno también exige su token.
+	w := serveRoute(router, "POST", "/api/register", tokens[1], `{"uuid":"router-a","direction":"SUR","speed":5}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("registro de un UUID ajeno: código %d, se esperaba 401", w.Code)
+	}
+}
+
+func TestRouterAdminKey(t *testing.T) {
+	freshSimulation(t)
+	prevKey := adminKey
+	t.Cleanup(func() { adminKey = prevKey })
+
+	// Sin clave configurada la API de administración está desactivada, aunque se envíe una.
+	adminKey = ""
+	if w := serveRoute(newRouter(false), "GET", "/api/admin/policy", "k", ""); w.Code != http.StatusForbidden {
+		t.Errorf("sin -admin-key: código %d, se esperaba 403", w.Code)
+	}
+
+	adminKey = "k"
+	router := newRouter(false)
+	mutex.Lock()
+	audited := auditSeq
+	mutex.Unlock()
+	for _, token := range []string{"", "otra"} {
+		if w := serveRoute(router, "POST", "/api/admin/policy", token, `{"policy":"alternate"}`); w.Code != http.StatusUnauthorized {
+			t.Errorf("clave %q: código %d, se esperaba 401", token, w.Code)
+		}
+	}
+	if w := serveRoute(router, "POST", "/api/admin/policy", "k", `{"policy":"alternate"}`); w.Code != http.StatusOK {
+		t.Fatalf("con la clave: código %d %s", w.Code, w.Body)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if activePolicy != "alternate" {
+		t.Errorf("política = %q tras el cambio autorizado", activePolicy)
+	}
+	// Los dos rechazos y el cambio quedan en la auditoría, en orden.
+	entries := auditLog[len(auditLog)-3:]
+	if auditSeq-audited != 3 || entries[0].Authorized || entries[1].Authorized || !entries[2].Authorized {
+		t.Errorf("auditoría = %+v", entries)
+	}
+	if entries[0].Status != http.StatusUnauthorized || entries[2].Action != "POST /api/admin/policy" {
+		t.Errorf("auditoría = %+v", entries)
+	}
+}
+
+// La reproducción sirve las consultas pero ninguna ruta que cambie el estado, ni siquiera con la clave.
+func TestRouterReadOnly(t *testing.T) {
+	freshSimulation(t)
+	prevKey := adminKey
+	adminKey = "k"
+	t.Cleanup(func() { adminKey = prevKey })
+	router := newRouter(true)
+
+	if w := serveRoute(router, "GET", "/api/status", "", ""); w.Code != http.StatusOK {
+		t.Errorf("GET /api/status: código %d", w.Code)
+	}
+	if w := serveRoute(router, "POST", "/api/register", "", `{"uuid":"ro","direction":"NORTE"}`); w.Code != http.StatusNotFound {
+		t.Errorf("POST /api/register: código %d, se esperaba 404", w.Code)
+	}
+	if w := serveRoute(router, "GET", "/api/admin/audit", "k", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET /api/admin/audit: código %d, se esperaba 404", w.Code)
+	}
+	// La ruta existe para consultar el programa, pero no para añadir un cierre.
+	if w := serveRoute(router, "POST", "/api/bridge/schedule", "k", "{}"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /api/bridge/schedule: código %d, se esperaba 405", w.Code)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(allCars) != 0 {
+		t.Errorf("hay %d coches registrados en modo de solo lectura", len(allCars))
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// Acumulados de una dirección de tráfico.
+type directionTotals struct {
+	Grants       int           `json:"grants"`
+	Crossings    int           `json:"crossings"`
+	TotalWait    time.Duration `json:"total_wait"`
+	MaxWait      time.Duration `json:"max_wait"`
+	TotalCrossed time.Duration `json:"total_crossed"`
+	WaitHist     histogram     `json:"wait_hist"`
+	CrossHist    histogram     `json:"cross_hist"`
+	// Mayor tiempo que la dirección tuvo coches en cola sin recibir paso.
+	LongestStarvation time.Duration `json:"longest_starvation"`
+	// Coches de esta dirección adelantados por uno de la otra dirección llegado después.
+	Overtakes int `json:"overtakes"`
+
+	// Inicio de la espera sin paso en curso; cero si la cola está vacía.
+	starvingSince time.Time
+}
+
+// Estadísticas globales del puente. Se llevan aparte de los coches para que sobrevivan a su baja.
+type bridgeTotals struct {
+	StartTime          time.Time                   `json:"start_time"`
+	BusyTime           time.Duration               `json:"busy_time"`
+	DirectionSwitches  int                         `json:"direction_switches"`
+	LastDirection      string                      `json:"last_direction"`
+	AbandonedCrossings int                         `json:"abandoned_crossings"`
+	RevokedLeases      int                         `json:"revoked_leases"`
+	EvacuatedCrossings int                         `json:"evacuated_crossings,omitempty"`
+	Directions         map[string]*directionTotals `json:"directions"`
+
+	// Momento en que el puente se ocupó por última vez; cero si está libre.
+	busySince time.Time
+}
+
+// Estadísticas globales del puente. Protegidas por el mutex global.
+var bridgeStats = newBridgeTotals(time.Now())
+
+// Crea acumulados vacíos con ambas direcciones.
+func newBridgeTotals(start time.Time) bridgeTotals {
+	return bridgeTotals{
+		StartTime: start,
+		Directions: map[string]*directionTotals{
+			"NORTE": {},
+			"SUR":   {},
+		},
+	}
+}
+
+// Copia los acumulados para poder serializarlos fuera del mutex.
+func (b bridgeTotals) clone() bridgeTotals {
+	directions := make(map[string]*directionTotals, len(b.Directions))
+	for dir, totals := range b.Directions {
+		copied := *totals
+		copied.WaitHist = totals.WaitHist.clone()
+		copied.CrossHist = totals.CrossHist.clone()
+		directions[dir] = &copied
+	}
+	b.Directions = directions
+	return b
+}
+
+// Devuelve los acumulados de una dirección, creándolos si hace falta.
+func (b *bridgeTotals) direction(dir string) *directionTotals {
+	totals, ok := b.Directions[dir]
+	if !ok {
+		totals = &directionTotals{}
+		b.Directions[dir] = totals
+	}
+	return totals
+}
+
+// Registra que un coche entró al puente tras esperar wait. Debe llamarse con el mutex bloqueado.
+func noteBridgeGrant(dir string, wait time.Duration, at time.Time) {
+	if bridgeStats.LastDirection != "" && bridgeStats.LastDirection != dir {
+		bridgeStats.DirectionSwitches++
+	}
+	bridgeStats.LastDirection = dir
+	bridgeStats.busySince = at
+
+	totals := bridgeStats.direction(dir)
+	totals.Grants++
+	totals.TotalWait += wait
+	totals.MaxWait = max(totals.MaxWait, wait)
+	totals.WaitHist.observe(wait.Seconds())
+
+	// El paso corta la espera de la dirección; si quedan coches en su cola, empieza otra.
+	endStarvation(totals, at)
+	if (dir == "NORTE" && len(queueNorth) > 0) || (dir == "SUR" && len(queueSouth) > 0) {
+		totals.starvingSince = at
+	}
+}
+
+// Registra que el coche que cruzaba salió del puente. Debe llamarse con el mutex bloqueado.
+func noteBridgeExit(dir string, crossed time.Duration, at time.Time) {
+	noteBridgeRelease(at)
+	totals := bridgeStats.direction(dir)
+	totals.Crossings++
+	totals.TotalCrossed += crossed
+	totals.CrossHist.observe(crossed.Seconds())
+}
+
+// Acumula el tiempo de ocupación al liberarse el puente. Debe llamarse con el mutex bloqueado.
+func noteBridgeRelease(at time.Time) {
+	if !bridgeStats.busySince.IsZero() {
+		bridgeStats.BusyTime += at.Sub(bridgeStats.busySince)
+		bridgeStats.busySince = time.Time{}
+	}
+}
+
+// Calcula las cifras globales del puente en el instante indicado. Debe llamarse con el mutex bloqueado.
+func buildBridgeStats(now time.Time) BridgeStatsResponse {
+	uptime := now.Sub(bridgeStats.StartTime)
+	busy := bridgeStats.BusyTime
+	if !bridgeStats.busySince.IsZero() {
+		busy += now.Sub(bridgeStats.busySince)
+	}
+
+	resp := BridgeStatsResponse{
+		StartTime:          bridgeStats.StartTime,
+		UptimeSec:          uptime.Seconds(),
+		BusySec:            busy.Seconds(),
+		IdleSec:            max(uptime-busy, 0).Seconds(),
+		DirectionSwitches:  bridgeStats.DirectionSwitches,
+		AbandonedCrossings: bridgeStats.AbandonedCrossings,
+		RevokedLeases:      bridgeStats.RevokedLeases,
+		EvacuatedCrossings: bridgeStats.EvacuatedCrossings,
+		Directions:         make(map[string]DirectionStatsResponse),
+	}
+
+	var allWaits, allCrossings histogram
+	for dir, totals := range bridgeStats.Directions {
+		stats := DirectionStatsResponse{
+			Crossings:    totals.Crossings,
+			MaxWaitSec:   totals.MaxWait.Seconds(),
+			WaitTime:     totals.WaitHist.response(),
+			CrossingTime: totals.CrossHist.response(),
+		}
+		allWaits.merge(totals.WaitHist)
+		allCrossings.merge(totals.CrossHist)
+		if totals.Grants > 0 {
+			stats.AvgWaitSec = totals.TotalWait.Seconds() / float64(totals.Grants)
+		}
+		if totals.Crossings > 0 {
+			stats.AvgCrossingTimeSec = totals.TotalCrossed.Seconds() / float64(totals.Crossings)
+		}
+		resp.Directions[dir] = stats
+		resp.TotalCrossings += totals.Crossings
+	}
+
+	resp.WaitTime = allWaits.response()
+	resp.CrossingTime = allCrossings.response()
+
+	if uptime > 0 {
+		resp.UtilizationPercent = busy.Seconds() / uptime.Seconds() * 100
+		resp.ThroughputPerMin = float64(resp.TotalCrossings) / uptime.Minutes()
+	}
+	return resp
+}
+
+// Manejador HTTP que devuelve las estadísticas globales del puente.
+func getBridgeStatsHandler(w http.ResponseWriter, r *http.Request) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	respondWithJSON(w, http.StatusOK, buildBridgeStats(time.Now()))
+}
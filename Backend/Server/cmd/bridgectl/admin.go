@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/sdk"
+)
+
+// Muestra la política de paso activa o, con un nombre, la cambia.
+func runPolicy(ctx context.Context, client *sdk.Client, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("uso: bridgectl policy [nombre]")
+	}
+	var resp api.PolicyResponse
+	var err error
+	if len(args) == 1 {
+		resp, err = client.SetPolicy(ctx, args[0])
+	} else {
+		resp, err = client.Policy(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Política de paso: %s (disponibles: %s)\n", resp.Policy, strings.Join(resp.Available, ", "))
+	return nil
+}
+
+// Vuelca el estado completo de la simulación en JSON con sangría.
+func runState(ctx context.Context, client *sdk.Client) error {
+	state, err := client.DumpState(ctx)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, state, "", "  "); err != nil {
+		return err
+	}
+	fmt.Println(out.String())
+	return nil
+}
+
+// Muestra las últimas acciones de administración, de la más reciente a la más antigua.
+func runAudit(ctx context.Context, client *sdk.Client, args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	limit := fs.Int("n", 20, "cantidad de entradas a mostrar (hasta 500)")
+	fs.Parse(args)
+
+	audit, err := client.Audit(ctx, 1, *limit, true)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tHora\tAcción\tVehículo\tDetalle\tOrigen\tAutorizada\tEstado")
+	for _, e := range audit.Entries {
+		vehicle := "-"
+		if e.VehicleID != 0 {
+			vehicle = fmt.Sprint(e.VehicleID)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n", e.Seq, e.Time.Local().Format("2006-01-02 15:04:05"), e.Action, vehicle, e.Detail, e.Remote, yesNo(e.Authorized), e.Status)
+	}
+	tw.Flush()
+	fmt.Printf("%d de %d entradas\n", len(audit.Entries), audit.Total)
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/sdk"
+)
+
+// Cierra el puente con el modo, el motivo y la duración indicados.
+func runClose(ctx context.Context, client *sdk.Client, args []string) error {
+	fs := flag.NewFlagSet("close", flag.ExitOnError)
+	mode := fs.String("mode", "drain", "drain deja terminar al que cruza; immediate lo devuelve al frente de su cola")
+	reason := fs.String("reason", "", "motivo del cierre, visible en /api/status")
+	duration := fs.Duration("for", 0, "reabre el puente solo tras este tiempo (0 = hasta bridgectl open)")
+	fs.Parse(args)
+
+	req := api.CloseRequest{Mode: *mode, Reason: *reason}
+	if *duration > 0 {
+		reopen := time.Now().Add(*duration)
+		req.ReopenAt = &reopen
+	}
+	msg, err := client.CloseBridge(ctx, req)
+	if err != nil {
+		return err
+	}
+	fmt.Println(msg)
+	return nil
+}
+
+// Lista, programa o cancela cierres del puente.
+func runSchedule(ctx context.Context, client *sdk.Client, args []string) error {
+	if len(args) == 0 {
+		return listSchedule(ctx, client)
+	}
+	switch args[0] {
+	case "add":
+		return addScheduledClosure(ctx, client, args[1:])
+	case "cancel":
+		if len(args) != 2 {
+			return fmt.Errorf("uso: bridgectl schedule cancel <id>")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("ID de cierre inválido: %s", args[1])
+		}
+		msg, err := client.CancelScheduledClosure(ctx, id)
+		if err != nil {
+			return err
+		}
+		fmt.Println(msg)
+		return nil
+	default:
+		return fmt.Errorf("uso: bridgectl schedule [add|cancel]")
+	}
+}
+
+// Muestra los cierres programados pendientes.
+func listSchedule(ctx context.Context, client *sdk.Client) error {
+	closures, err := client.ClosureSchedule(ctx)
+	if err != nil {
+		return err
+	}
+	if len(closures) == 0 {
+		fmt.Println("No hay cierres programados.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDesde\tHasta\tModo\tMotivo")
+	for _, c := range closures {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", c.ID, c.StartAt.Local().Format(time.DateTime), c.EndAt.Local().Format(time.DateTime), c.Mode, c.Reason)
+	}
+	return tw.Flush()
+}
+
+// Programa un cierre. El inicio es una hora RFC 3339 o un plazo desde ahora; el fin, una hora o una duración desde el inicio.
+func addScheduledClosure(ctx context.Context, client *sdk.Client, args []string) error {
+	fs := flag.NewFlagSet("schedule add", flag.ExitOnError)
+	start := fs.String("start", "", "inicio: hora RFC 3339 o plazo desde ahora (p. ej. 10m)")
+	end := fs.String("end", "", "fin: hora RFC 3339 o duración desde el inicio (p. ej. 2h)")
+	mode := fs.String("mode", "drain", "modo de cierre: drain o immediate")
+	reason := fs.String("reason", "", "motivo del cierre, visible en /api/status")
+	fs.Parse(args)
+	if *start == "" || *end == "" {
+		return fmt.Errorf("uso: bridgectl schedule add -start INICIO -end FIN [-mode drain|immediate] [-reason MOTIVO]")
+	}
+
+	startAt, err := parseWhen(*start, time.Now())
+	if err != nil {
+		return fmt.Errorf("-start inválido: %v", err)
+	}
+	endAt, err := parseWhen(*end, startAt)
+	if err != nil {
+		return fmt.Errorf("-end inválido: %v", err)
+	}
+
+	scheduled, err := client.ScheduleClosure(ctx, api.ScheduleClosureRequest{Mode: *mode, Reason: *reason, StartAt: startAt, EndAt: endAt})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Cierre %d programado de %s a %s.\n", scheduled.ID, scheduled.StartAt.Local().Format(time.DateTime), scheduled.EndAt.Local().Format(time.DateTime))
+	return nil
+}
+
+// Interpreta una hora RFC 3339 o una duración sumada a base.
+func parseWhen(s string, base time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return base.Add(d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Describe un evento en una línea.
+func describeEvent(ev api.Event) string {
+	switch ev.Type {
+	case "server_started":
+		return "Servidor iniciado"
+	case "registered":
+		return fmt.Sprintf("Auto %d registrado vía %s hacia el %s", ev.CarID, ev.Transport, ev.Direction)
+	case "queued":
+		return fmt.Sprintf("Auto %d en cola %s, posición %d", ev.CarID, ev.Direction, ev.Position)
+	case "granted":
+		return fmt.Sprintf("Auto %d entra al puente hacia el %s tras esperar %.1fs", ev.CarID, ev.Direction, ev.WaitSec)
+	case "finished":
+		return fmt.Sprintf("Auto %d sale del puente tras %.1fs", ev.CarID, ev.DurationSec)
+	case "resting":
+		return fmt.Sprintf("Auto %d descansa %.0fs", ev.CarID, ev.RestSec)
+	case "stopped":
+		return fmt.Sprintf("Auto %d no volverá a la cola", ev.CarID)
+	case "cancelled":
+		return fmt.Sprintf("Auto %d retirado de la cola por un operador", ev.CarID)
+	case "bridge_closed":
+		return fmt.Sprintf("Puente cerrado (%s)", ev.Reason)
+	case "bridge_opened":
+		if ev.Reason == "scheduled" {
+			return "Puente abierto al terminar el cierre previsto"
+		}
+		return "Puente abierto"
+	case "evacuated":
+		return fmt.Sprintf("Auto %d evacuado del puente, vuelve al frente de la cola %s", ev.CarID, ev.Direction)
+	case "simulation_reset":
+		return "Simulación reiniciada por la administración"
+	default:
+		if ev.Reason != "" {
+			return fmt.Sprintf("Auto %d (%s)", ev.CarID, ev.Reason)
+		}
+		return fmt.Sprintf("Auto %d", ev.CarID)
+	}
+}
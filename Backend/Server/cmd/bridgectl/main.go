@@ -0,0 +1,266 @@
+// Bridgectl es la consola de operación del puente: muestra su estado en vivo y ejecuta acciones
+// de administración a través de la API REST, sin necesidad del frontend.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/sdk"
+)
+
+// Texto de ayuda con los comandos disponibles.
+const usage = `Uso: bridgectl [-server URL] [-admin-key CLAVE] <comando> [argumentos]
+
+Comandos:
+  status               estado del puente y de las colas
+  watch                panel en vivo que se actualiza con el flujo de eventos
+  vehicles [opciones]  lista los vehículos (ver bridgectl vehicles -h)
+  events               muestra el flujo de eventos, uno por línea
+  stop -token T <id>   el vehículo no vuelve a la cola tras su próximo cruce
+  cancel -token T <id> retira de la cola a un vehículo en espera o en descanso
+
+Comandos de administración (requieren -admin-key):
+  evict <id>           elimina a un vehículo del sistema
+  close [opciones]      cierra el puente (ver bridgectl close -h)
+  open                 reabre el puente
+  schedule             lista los cierres programados
+  schedule add [opc.]  programa un cierre (ver bridgectl schedule add -h)
+  schedule cancel <id> cancela un cierre programado
+  reset                reinicia la simulación: retira a todos los vehículos
+  policy [nombre]      muestra la política de paso o la cambia
+  state                vuelca el estado completo de la simulación en JSON
+  audit [-n N]         muestra las últimas acciones de administración
+
+Opciones:
+`
+
+func main() {
+	server := flag.String("server", envOr("BRIDGE_SERVER", "localhost:8080"), "dirección de la API del servidor (o variable BRIDGE_SERVER)")
+	adminKey := flag.String("admin-key", os.Getenv("BRIDGE_ADMIN_KEY"), "clave de administración del servidor (o variable BRIDGE_ADMIN_KEY)")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	client := sdk.New(*server)
+	if *adminKey != "" {
+		client = client.WithAdminKey(*adminKey)
+	}
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+
+	var err error
+	switch cmd {
+	case "status":
+		err = runStatus(ctx, client)
+	case "watch":
+		err = runWatch(ctx, client)
+	case "vehicles":
+		err = runVehicles(ctx, client, args)
+	case "events":
+		err = runEvents(ctx, client)
+	case "stop", "cancel", "evict":
+		err = runVehicleAction(ctx, client, cmd, args, *adminKey)
+	case "close":
+		err = runClose(ctx, client, args)
+	case "open":
+		var msg string
+		if msg, err = client.OpenBridge(ctx); err == nil {
+			fmt.Println(msg)
+		}
+	case "schedule":
+		err = runSchedule(ctx, client, args)
+	case "reset":
+		var msg string
+		if msg, err = client.Reset(ctx); err == nil {
+			fmt.Println(msg)
+		}
+	case "policy":
+		err = runPolicy(ctx, client, args)
+	case "state":
+		err = runState(ctx, client)
+	case "audit":
+		err = runAudit(ctx, client, args)
+	default:
+		fmt.Fprintf(os.Stderr, "Comando desconocido: %s\n\n", cmd)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// Devuelve el valor de la variable de entorno, o def si no está definida.
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// Muestra una vez el estado del puente y de las colas.
+func runStatus(ctx context.Context, client *sdk.Client) error {
+	status, err := client.Status(ctx)
+	if err != nil {
+		return err
+	}
+	q, err := client.Queue(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(describeBridge(status))
+	fmt.Printf("Cola NORTE (%d): %s\n", len(q.North), describeQueue(q.North, 0))
+	fmt.Printf("Cola SUR (%d): %s\n", len(q.South), describeQueue(q.South, 0))
+	return nil
+}
+
+// Lista los vehículos con los filtros y el orden indicados.
+func runVehicles(ctx context.Context, client *sdk.Client, args []string) error {
+	fs := flag.NewFlagSet("vehicles", flag.ExitOnError)
+	status := fs.String("status", "", "filtra por estado (waiting, crossing, finished, cancelled...)")
+	direction := fs.String("direction", "", "filtra por dirección (NORTE o SUR)")
+	sortBy := fs.String("sort", "registered", "orden: registered, crossings o avg_wait")
+	desc := fs.Bool("desc", false, "orden descendente")
+	limit := fs.Int("limit", 50, "máximo de vehículos a mostrar (hasta 500)")
+	fs.Parse(args)
+
+	list, err := client.Vehicles(ctx, sdk.VehicleQuery{
+		Status:    *status,
+		Direction: *direction,
+		Sort:      *sortBy,
+		Desc:      *desc,
+		PageSize:  *limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUUID\tDirección\tVel.\tEstado\tBucle\tCruces\tEspera prom.")
+	for _, v := range list.Vehicles {
+		uuid := v.UUID
+		if v.Synthetic {
+			uuid += " (sintético)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\t%d\t%.1fs\n", v.ID, uuid, v.Direction, v.Speed, v.Status, yesNo(v.IsLooping), v.Crossings, v.AvgWaitSec)
+	}
+	tw.Flush()
+	fmt.Printf("%d de %d vehículos\n", len(list.Vehicles), list.Total)
+	return nil
+}
+
+// Muestra los eventos del puente a medida que llegan, hasta Ctrl+C.
+func runEvents(ctx context.Context, client *sdk.Client) error {
+	return client.Events(ctx, func(ev api.Event) {
+		fmt.Printf("%s  #%-6d %-14s %s\n", ev.Time.Local().Format("15:04:05"), ev.Seq, ev.Type, describeEvent(ev))
+	})
+}
+
+// Ejecuta stop, cancel o evict sobre un vehículo. Sin -token, stop y cancel usan la clave de administración.
+func runVehicleAction(ctx context.Context, client *sdk.Client, cmd string, args []string, adminKey string) error {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	token := fs.String("token", "", "token del vehículo, devuelto por /api/register (para stop y cancel sin -admin-key)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("uso: bridgectl %s [-token TOKEN] <id>", cmd)
+	}
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("ID de vehículo inválido: %s", fs.Arg(0))
+	}
+	if *token == "" {
+		*token = adminKey
+	}
+
+	var msg string
+	switch cmd {
+	case "stop":
+		msg, err = client.Stop(ctx, id, *token)
+	case "cancel":
+		msg, err = client.Cancel(ctx, id, *token)
+	default:
+		msg, err = client.Evict(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(msg)
+	return nil
+}
+
+// Resume en una línea si el puente está abierto, quién cruza y el semáforo.
+func describeBridge(s api.BridgeStatus) string {
+	state := "ABIERTO"
+	if s.Closed {
+		state = "CERRADO"
+	}
+	occupancy := "libre"
+	if s.Busy {
+		occupancy = fmt.Sprintf("ocupado por el auto %d hacia el %s", s.CurrentCarID, s.CurrentDir)
+	}
+	if c := s.Closure; c != nil {
+		state += " (" + c.Mode
+		if c.Reason != "" {
+			state += ": " + c.Reason
+		}
+		if c.ReopenAt != nil {
+			state += ", reabre a las " + c.ReopenAt.Local().Format("15:04")
+		}
+		state += ")"
+	}
+	light := "rojo"
+	if s.TrafficLight == "green" {
+		light = "verde"
+	}
+	return fmt.Sprintf("Puente %s · %s · semáforo %s", state, occupancy, light)
+}
+
+// Lista los coches de una cola; con limit > 0 muestra solo los primeros.
+func describeQueue(queue []api.Car, limit int) string {
+	if len(queue) == 0 {
+		return "vacía"
+	}
+	var out string
+	for i, car := range queue {
+		if limit > 0 && i == limit {
+			return out + fmt.Sprintf(" +%d", len(queue)-limit)
+		}
+		if i > 0 {
+			out += "  "
+		}
+		out += fmt.Sprintf("#%d (vel %d)", car.ID, car.Speed)
+	}
+	return out
+}
+
+// Devuelve "sí" o "no".
+func yesNo(b bool) string {
+	if b {
+		return "sí"
+	}
+	return "no"
+}
+
+// Hora actual en formato corto, para el panel.
+func clock() string {
+	return time.Now().Format("15:04:05")
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/sdk"
+)
+
+// Parámetros del panel en vivo.
+const (
+	// Eventos recientes que se muestran al pie del panel.
+	watchEventLines = 12
+	// Coches de cada cola que caben en una línea.
+	watchQueueWidth = 8
+	// El panel se redibuja aunque no lleguen eventos, por si el flujo está caído.
+	watchRefresh = 2 * time.Second
+	// Los eventos se agrupan para no redibujar más de unas pocas veces por segundo.
+	watchDebounce = 250 * time.Millisecond
+	// Espera antes de volver a abrir el flujo de eventos si se corta.
+	watchReconnect = 2 * time.Second
+)
+
+// Estado del panel compartido entre la lectura del flujo de eventos y el dibujo.
+type watchState struct {
+	mu        sync.Mutex
+	events    []api.Event
+	connected bool
+	streamErr error
+	dirty     bool
+}
+
+// Añade un evento reciente y marca el panel para redibujarlo.
+func (s *watchState) add(ev api.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, ev)
+	if len(s.events) > watchEventLines {
+		s.events = s.events[len(s.events)-watchEventLines:]
+	}
+	s.connected = true
+	s.dirty = true
+}
+
+// Anota si el flujo de eventos está conectado.
+func (s *watchState) setStream(connected bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected, s.streamErr, s.dirty = connected, err, true
+}
+
+// Panel en vivo: redibuja el estado del puente con cada evento recibido y periódicamente.
+func runWatch(ctx context.Context, client *sdk.Client) error {
+	state := &watchState{dirty: true}
+
+	// Mantiene abierto el flujo de eventos, reconectando si se corta.
+	go func() {
+		for ctx.Err() == nil {
+			state.setStream(true, nil)
+			err := client.Events(ctx, state.add)
+			if ctx.Err() != nil {
+				return
+			}
+			state.setStream(false, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(watchReconnect):
+			}
+		}
+	}()
+
+	// Oculta el cursor mientras el panel está activo.
+	fmt.Print("\033[?25l")
+	defer fmt.Print("\033[?25h\n")
+
+	debounce := time.NewTicker(watchDebounce)
+	defer debounce.Stop()
+	lastDraw := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-debounce.C:
+			state.mu.Lock()
+			redraw := state.dirty || time.Since(lastDraw) >= watchRefresh
+			state.dirty = false
+			state.mu.Unlock()
+			if redraw {
+				drawWatch(ctx, client, state)
+				lastDraw = time.Now()
+			}
+		}
+	}
+}
+
+// Consulta el estado actual y dibuja el panel completo.
+func drawWatch(ctx context.Context, client *sdk.Client, state *watchState) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Puente de una vía · %s · %s   (Ctrl+C para salir)\n\n", client.BaseURL(), clock())
+
+	var q api.QueueResponse
+	var stats api.BridgeStatsResponse
+	status, err := client.Status(ctx)
+	if err == nil {
+		q, err = client.Queue(ctx)
+	}
+	if err == nil {
+		stats, err = client.BridgeStats(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "No se pudo consultar el servidor: %v\n", err)
+	} else {
+		fmt.Fprintln(&b, describeBridge(status))
+		fmt.Fprintf(&b, "\nCola NORTE (%d): %s\n", len(q.North), describeQueue(q.North, watchQueueWidth))
+		fmt.Fprintf(&b, "Cola SUR   (%d): %s\n", len(q.South), describeQueue(q.South, watchQueueWidth))
+		fmt.Fprintf(&b, "\nCruces: %d · %.1f por minuto · utilización %.0f%%\n", stats.TotalCrossings, stats.ThroughputPerMin, stats.UtilizationPercent)
+		for _, dir := range []string{"NORTE", "SUR"} {
+			d := stats.Directions[dir]
+			fmt.Fprintf(&b, "  %-5s  %d cruces, espera promedio %.1fs\n", dir, d.Crossings, d.AvgWaitSec)
+		}
+	}
+
+	state.mu.Lock()
+	fmt.Fprintln(&b, "\nÚltimos eventos")
+	if len(state.events) == 0 {
+		fmt.Fprintln(&b, "  (ninguno todavía)")
+	}
+	for _, ev := range state.events {
+		fmt.Fprintf(&b, "  %s  %-14s %s\n", ev.Time.Local().Format("15:04:05"), ev.Type, describeEvent(ev))
+	}
+	if state.connected {
+		fmt.Fprintln(&b, "\nFlujo de eventos: conectado")
+	} else {
+		fmt.Fprintf(&b, "\nFlujo de eventos: desconectado (%v), reintentando...\n", state.streamErr)
+	}
+	state.mu.Unlock()
+
+	// Vuelve al inicio y borra la pantalla antes de escribir todo de una vez, para evitar parpadeos.
+	fmt.Fprint(os.Stdout, "\033[H\033[2J"+b.String())
+}
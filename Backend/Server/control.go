@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Eventos que puede acumular un suscriptor de /api/events antes de desconectarlo.
+const eventSubscriberBuffer = 256
+
+// Cada cuánto se envía un comentario a los suscriptores para mantener viva la conexión.
+const eventKeepAlive = 15 * time.Second
+
+// Suscribe un cliente al flujo de eventos en vivo.
+func subscribeEvents() chan bridgeEvent {
+	ch := make(chan bridgeEvent, eventSubscriberBuffer)
+	mutex.Lock()
+	eventSubscribers[ch] = struct{}{}
+	mutex.Unlock()
+	return ch
+}
+
+// Cancela la suscripción, salvo que recordEvent ya la haya cerrado por lenta.
+func unsubscribeEvents(ch chan bridgeEvent) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if _, ok := eventSubscribers[ch]; ok {
+		delete(eventSubscribers, ch)
+		close(ch)
+	}
+}
+
+// Manejador HTTP que envía los eventos del puente a medida que ocurren, como Server-Sent Events.
+func streamEventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "El servidor no admite respuestas en streaming")
+		return
+	}
+
+	events := subscribeEvents()
+	defer unsubscribeEvents(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": conectado\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				log.Printf("[Eventos] Suscriptor %s demasiado lento. Desconectado.", r.RemoteAddr)
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, data)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// Manejador HTTP que retira de la cola a un coche en espera o en descanso y detiene su ciclo.
+func cancelVehicleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de vehículo inválido")
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	car, exists := allCars[id]
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "Vehículo no encontrado")
+		return
+	}
+	if currentCar != nil && currentCar.ID == id {
+		respondWithError(w, http.StatusConflict, "El vehículo ya está cruzando el puente")
+		return
+	}
+	if car.Status == "cancelled" {
+		respondWithError(w, http.StatusConflict, "El vehículo ya fue cancelado")
+		return
+	}
+
+	// El coche queda registrado como cancelado para que su dueño vea el estado y sus estadísticas.
+	car.Status = "cancelled"
+	car.IsLooping = false
+	car.CanRequeueAt = 0
+	allCars[id] = car
+	queueNorth = removeCarFromSlice(queueNorth, id)
+	queueSouth = removeCarFromSlice(queueSouth, id)
+	noteQueueChange(time.Now())
+	disconnectSession(id, "cancelled", "La solicitud de cruce fue cancelada")
+	notifyQueuePositions()
+	journalChange(id)
+	recordEvent(carEvent(evCancelled, car))
+
+	log.Printf("[Auto %d] Solicitud de cruce cancelada.", id)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Solicitud de cruce cancelada."})
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// Tipos de evento del ciclo de vida del puente.
+const (
+	evServerStarted = "server_started"
+	evRegistered    = "registered"
+	evQueued        = "queued"
+	evGranted       = "granted"
+	evFinished      = "finished"
+	evResting       = "resting"
+	evStopped       = "stopped"
+	evRemoved       = "removed"
+	evAbandoned     = "abandoned"
+	evRevoked       = "revoked"
+	evCancelled     = "cancelled"
+	evBridgeClosed  = "bridge_closed"
+	evBridgeOpened  = "bridge_opened"
+	// Un cierre inmediato sacó del puente al coche que cruzaba y lo devolvió al frente de su cola.
+	evEvacuated = "evacuated"
+	// La administración reinició la simulación: se retiraron todos los vehículos.
+	evSimulationReset = "simulation_reset"
+)
+
+// Variables del registro de eventos.
+var (
+	// Ruta del registro de eventos (JSON por líneas); vacío para desactivarlo.
+	eventsFile string
+	// Número de secuencia del último evento emitido. Protegido por el mutex global.
+	eventSeq uint64
+	// Eventos pendientes de escribir; es nil si el registro está desactivado. Protegido por el mutex global.
+	eventQueue *writeQueue[bridgeEvent]
+	// Se cierra cuando la rutina de escritura terminó de vaciar la cola.
+	eventsDone chan struct{}
+	// Canales de los clientes suscritos a /api/events. Protegido por el mutex global.
+	eventSubscribers = make(map[chan bridgeEvent]struct{})
+)
+
+// Abre el registro de eventos en modo de solo añadir y continúa su numeración.
+func startEventLog() {
+	if eventsFile == "" {
+		return
+	}
+
+	lastSeq, err := lastEventSeq(eventsFile)
+	if err != nil {
+		log.Printf("[Eventos] No se pudo leer %s: %v. Registro de eventos desactivado.", eventsFile, err)
+		return
+	}
+
+	file, err := os.OpenFile(eventsFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Printf("[Eventos] No se pudo abrir %s: %v. Registro de eventos desactivado.", eventsFile, err)
+		return
+	}
+
+	mutex.Lock()
+	eventSeq = lastSeq
+	eventQueue = newWriteQueue[bridgeEvent]()
+	eventsDone = make(chan struct{})
+	go eventLoop(file, eventQueue)
+	recordEvent(bridgeEvent{Type: evServerStarted})
+	mutex.Unlock()
+}
+
+// Espera a que se escriban los eventos pendientes y cierra el registro.
+func stopEventLog() {
+	mutex.Lock()
+	if eventQueue == nil {
+		mutex.Unlock()
+		return
+	}
+	eventQueue.close()
+	eventQueue = nil
+	mutex.Unlock()
+
+	<-eventsDone
+}
+
+// Crea un evento con los datos que identifican al coche.
+func carEvent(evType string, car Car) bridgeEvent {
+	return bridgeEvent{
+		Type:      evType,
+		CarID:     car.ID,
+		UUID:      car.UUID,
+		Direction: car.Direction,
+		Speed:     car.Speed,
+	}
+}
+
+// Asigna número de secuencia y marca de tiempo al evento y lo envía al registro. Debe llamarse con el mutex bloqueado.
+func recordEvent(ev bridgeEvent) {
+	eventSeq++
+	ev.Seq = eventSeq
+	ev.Time = time.Now()
+
+	// La cola no tiene límite: un disco lento no frena la simulación y el registro no pierde eventos.
+	if eventQueue != nil {
+		eventQueue.push(ev)
+	}
+	for ch := range eventSubscribers {
+		select {
+		case ch <- ev:
+		default:
+			// Un suscriptor que no consume sus eventos se desconecta en lugar de frenar la simulación.
+			delete(eventSubscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Escribe los eventos en el archivo, uno por línea, en el orden en que se emitieron.
+func eventLoop(file *os.File, queue *writeQueue[bridgeEvent]) {
+	defer close(eventsDone)
+
+	w := bufio.NewWriter(file)
+	for {
+		events, ok := queue.take()
+		if !ok {
+			break
+		}
+		for _, ev := range events {
+			data, err := json.Marshal(ev)
+			if err != nil {
+				log.Printf("[Eventos] Error codificando el evento %d: %v", ev.Seq, err)
+				continue
+			}
+			w.Write(append(data, '\n'))
+		}
+		if err := w.Flush(); err != nil {
+			log.Printf("[Eventos] Error escribiendo los eventos hasta el %d: %v", events[len(events)-1].Seq, err)
+			w.Reset(file)
+		}
+	}
+	file.Close()
+}
+
+// Devuelve el número de secuencia del último evento del archivo, o 0 si no existe.
+func lastEventSeq(path string) (uint64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var last uint64
+	err = readEvents(file, func(ev bridgeEvent) error {
+		last = max(last, ev.Seq)
+		return nil
+	})
+	return last, err
+}
+
+// Recorre los eventos de un registro. Las líneas ilegibles, como una última línea cortada, se descartan.
+func readEvents(r io.Reader, fn func(bridgeEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev bridgeEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
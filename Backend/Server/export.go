@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Formato de las fechas en los CSV: lo reconocen las hojas de cálculo y conserva los milisegundos.
+const exportTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Filas que se escriben entre dos envíos parciales de la respuesta.
+const exportFlushEvery = 500
+
+// Manejador HTTP que exporta los cruces registrados en CSV o JSON según la extensión de la ruta.
+func exportCrossingsHandler(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, ok := parseExportRange(w, r)
+		if !ok {
+			return
+		}
+
+		mutex.Lock()
+		crossings := collectCrossings(from, to)
+		mutex.Unlock()
+
+		if format == "json" {
+			streamJSON(w, "cruces.json", len(crossings), func(i int) interface{} { return crossings[i] })
+			return
+		}
+		header := []string{"car_id", "direction", "queued_at", "granted_at", "exited_at", "wait_sec", "duration_sec", "queue_length_at_arrival", "times_overtaken"}
+		streamCSV(w, "cruces.csv", header, len(crossings), func(i int) []string {
+			c := crossings[i]
+			return []string{
+				strconv.Itoa(c.CarID),
+				c.Direction,
+				c.QueuedAt.Format(exportTimeFormat),
+				c.GrantedAt.Format(exportTimeFormat),
+				c.ExitedAt.Format(exportTimeFormat),
+				formatSeconds(c.WaitSec),
+				formatSeconds(c.DurationSec),
+				strconv.Itoa(c.QueueLengthAtArrival),
+				strconv.Itoa(c.TimesOvertaken),
+			}
+		})
+	}
+}
+
+// Manejador HTTP que exporta el resumen de cada vehículo en CSV o JSON según la extensión de la ruta.
+func exportVehiclesHandler(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, ok := parseExportRange(w, r)
+		if !ok {
+			return
+		}
+
+		mutex.Lock()
+		vehicles := summarizeVehicles(collectCrossings(from, to))
+		mutex.Unlock()
+
+		if format == "json" {
+			streamJSON(w, "vehiculos.json", len(vehicles), func(i int) interface{} { return vehicles[i] })
+			return
+		}
+		header := []string{"vehicle_id", "uuid", "status", "crossings", "total_wait_sec", "avg_wait_sec", "max_wait_sec", "total_crossing_sec", "avg_crossing_sec", "times_overtaken", "first_crossing_at", "last_crossing_at"}
+		streamCSV(w, "vehiculos.csv", header, len(vehicles), func(i int) []string {
+			v := vehicles[i]
+			return []string{
+				strconv.Itoa(v.VehicleID),
+				v.UUID,
+				v.Status,
+				strconv.Itoa(v.Crossings),
+				formatSeconds(v.TotalWaitSec),
+				formatSeconds(v.AvgWaitSec),
+				formatSeconds(v.MaxWaitSec),
+				formatSeconds(v.TotalCrossingSec),
+				formatSeconds(v.AvgCrossingSec),
+				strconv.Itoa(v.TimesOvertaken),
+				formatOptionalTime(v.FirstCrossingAt),
+				formatOptionalTime(v.LastCrossingAt),
+			}
+		})
+	}
+}
+
+// Lee el rango from/to de la URL; responde con un error y devuelve false si no es válido.
+func parseExportRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	query := r.URL.Query()
+	from, errFrom := parseTimeParam(query.Get("from"), time.Time{})
+	to, errTo := parseTimeParam(query.Get("to"), time.Now())
+	if errFrom != nil || errTo != nil {
+		respondWithError(w, http.StatusBadRequest, "Rango inválido: use segundos Unix o RFC 3339 en 'from' y 'to'")
+		return from, to, false
+	}
+	if to.Before(from) {
+		respondWithError(w, http.StatusBadRequest, "'from' debe ser anterior a 'to'")
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// Copia, en orden de salida, los cruces que terminaron entre from y to. Debe llamarse con el mutex bloqueado.
+func collectCrossings(from, to time.Time) []CrossingRecord {
+	var crossings []CrossingRecord
+	for _, history := range crossingHistory {
+		for _, record := range history {
+			if record.ExitedAt.Before(from) || record.ExitedAt.After(to) {
+				continue
+			}
+			crossings = append(crossings, record)
+		}
+	}
+	sort.Slice(crossings, func(i, j int) bool {
+		if !crossings[i].ExitedAt.Equal(crossings[j].ExitedAt) {
+			return crossings[i].ExitedAt.Before(crossings[j].ExitedAt)
+		}
+		return crossings[i].CarID < crossings[j].CarID
+	})
+	return crossings
+}
+
+// Resume los cruces por vehículo, incluyendo los vehículos registrados que aún no cruzaron.
+// Debe llamarse con el mutex bloqueado.
+func summarizeVehicles(crossings []CrossingRecord) []VehicleSummary {
+	uuids := make(map[int]string, len(clientRegistry))
+	for uuid, id := range clientRegistry {
+		uuids[id] = uuid
+	}
+
+	byID := make(map[int]*VehicleSummary)
+	summary := func(id int) *VehicleSummary {
+		v, ok := byID[id]
+		if !ok {
+			v = &VehicleSummary{VehicleID: id, UUID: uuids[id], Status: "removed"}
+			if car, exists := allCars[id]; exists {
+				v.UUID = car.UUID
+				v.Status = car.Status
+			}
+			byID[id] = v
+		}
+		return v
+	}
+
+	for id := range allCars {
+		summary(id)
+	}
+	for _, c := range crossings {
+		v := summary(c.CarID)
+		v.Crossings++
+		v.TotalWaitSec += c.WaitSec
+		v.MaxWaitSec = max(v.MaxWaitSec, c.WaitSec)
+		v.TotalCrossingSec += c.DurationSec
+		v.TimesOvertaken += c.TimesOvertaken
+		if v.FirstCrossingAt == nil {
+			first := c.ExitedAt
+			v.FirstCrossingAt = &first
+		}
+		last := c.ExitedAt
+		v.LastCrossingAt = &last
+	}
+
+	vehicles := make([]VehicleSummary, 0, len(byID))
+	for _, v := range byID {
+		if v.Crossings > 0 {
+			v.AvgWaitSec = v.TotalWaitSec / float64(v.Crossings)
+			v.AvgCrossingSec = v.TotalCrossingSec / float64(v.Crossings)
+		}
+		vehicles = append(vehicles, *v)
+	}
+	sort.Slice(vehicles, func(i, j int) bool { return vehicles[i].VehicleID < vehicles[j].VehicleID })
+	return vehicles
+}
+
+// Escribe las filas como CSV, enviando la respuesta por partes para no acumularla en memoria.
+func streamCSV(w http.ResponseWriter, filename string, header []string, n int, row func(int) []string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for i := 0; i < n; i++ {
+		if err := cw.Write(row(i)); err != nil {
+			return
+		}
+		if i%exportFlushEvery == exportFlushEvery-1 {
+			cw.Flush()
+			flushResponse(w)
+		}
+	}
+	cw.Flush()
+}
+
+// Escribe los elementos como un arreglo JSON, codificándolos de uno en uno.
+func streamJSON(w http.ResponseWriter, filename string, n int, item func(int) interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	w.Write([]byte("["))
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			w.Write([]byte(","))
+		}
+		data, err := json.Marshal(item(i))
+		if err != nil {
+			return
+		}
+		if _, err := w.Write(data); err != nil {
+			return
+		}
+		if i%exportFlushEvery == exportFlushEvery-1 {
+			flushResponse(w)
+		}
+	}
+	w.Write([]byte("]\n"))
+}
+
+// Envía al cliente lo escrito hasta ahora, si la conexión lo permite.
+func flushResponse(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Formatea segundos con tres decimales, suficiente para milisegundos.
+func formatSeconds(sec float64) string {
+	return strconv.FormatFloat(sec, 'f', 3, 64)
+}
+
+// Formatea una fecha opcional; vacío si no hay fecha.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(exportTimeFormat)
+}
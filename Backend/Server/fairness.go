@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Abre o cierra los periodos de espera sin paso según haya coches en cada cola. Debe llamarse con el mutex bloqueado.
+func noteQueueChange(now time.Time) {
+	for dir, queue := range map[string][]Car{"NORTE": queueNorth, "SUR": queueSouth} {
+		totals := bridgeStats.direction(dir)
+		if len(queue) > 0 && totals.starvingSince.IsZero() {
+			totals.starvingSince = now
+		} else if len(queue) == 0 {
+			endStarvation(totals, now)
+		}
+	}
+}
+
+// Cierra el periodo de espera sin paso de una dirección y guarda su duración si es la mayor. Debe llamarse con el mutex bloqueado.
+func endStarvation(totals *directionTotals, now time.Time) {
+	if totals.starvingSince.IsZero() {
+		return
+	}
+	totals.LongestStarvation = max(totals.LongestStarvation, now.Sub(totals.starvingSince))
+	totals.starvingSince = time.Time{}
+}
+
+// Cuenta un adelantamiento a cada coche de la otra dirección que llegó a la cola antes que el que recibe paso.
+// Debe llamarse con el mutex bloqueado.
+func noteOvertakes(granted Car) {
+	arrival := granted.TimeEnteredQueue
+	if c, exists := allCars[granted.ID]; exists {
+		arrival = c.TimeEnteredQueue
+	}
+
+	other := queueSouth
+	if granted.Direction == "SUR" {
+		other = queueNorth
+	}
+	for _, queued := range other {
+		c, exists := allCars[queued.ID]
+		if !exists || !c.TimeEnteredQueue.Before(arrival) {
+			continue
+		}
+		c.OvertakenInQueue++
+		allCars[c.ID] = c
+		bridgeStats.direction(c.Direction).Overtakes++
+		journalChange(c.ID)
+	}
+}
+
+// Calcula el índice de Jain de un conjunto de valores; 1 si todos son iguales o no hay valores.
+func jainIndex(values []float64) float64 {
+	var sum, sumSquares float64
+	for _, v := range values {
+		sum += v
+		sumSquares += v * v
+	}
+	if sumSquares == 0 {
+		return 1
+	}
+	return sum * sum / (float64(len(values)) * sumSquares)
+}
+
+// Calcula los indicadores de equidad en el instante indicado. Debe llamarse con el mutex bloqueado.
+func buildFairness(now time.Time) FairnessResponse {
+	resp := FairnessResponse{
+		Directions: make(map[string]DirectionFairness),
+		Vehicles:   []VehicleFairness{},
+	}
+
+	for dir, totals := range bridgeStats.Directions {
+		stats := DirectionFairness{
+			LongestStarvationSec: totals.LongestStarvation.Seconds(),
+			Overtakes:            totals.Overtakes,
+		}
+		if totals.Grants > 0 {
+			stats.AvgWaitSec = totals.TotalWait.Seconds() / float64(totals.Grants)
+		}
+		if !totals.starvingSince.IsZero() {
+			stats.CurrentStarvationSec = now.Sub(totals.starvingSince).Seconds()
+			stats.LongestStarvationSec = max(stats.LongestStarvationSec, stats.CurrentStarvationSec)
+		}
+		resp.Directions[dir] = stats
+	}
+	if south := resp.Directions["SUR"].AvgWaitSec; south > 0 {
+		ratio := resp.Directions["NORTE"].AvgWaitSec / south
+		resp.WaitRatioNorthSouth = &ratio
+	}
+
+	// Los vehículos dados de baja siguen contando gracias a su historial.
+	vehicles := make(map[int]*VehicleFairness)
+	for id, history := range crossingHistory {
+		v := &VehicleFairness{VehicleID: id, Crossings: len(history)}
+		for _, record := range history {
+			v.AvgWaitSec += record.WaitSec
+			v.TimesOvertaken += record.TimesOvertaken
+		}
+		if len(history) > 0 {
+			v.AvgWaitSec /= float64(len(history))
+		}
+		vehicles[id] = v
+	}
+	// Los adelantamientos de la espera en curso aún no están en el historial.
+	for id, car := range allCars {
+		if car.OvertakenInQueue == 0 {
+			continue
+		}
+		v, ok := vehicles[id]
+		if !ok {
+			v = &VehicleFairness{VehicleID: id}
+			vehicles[id] = v
+		}
+		v.TimesOvertaken += car.OvertakenInQueue
+	}
+
+	var waits []float64
+	for _, v := range vehicles {
+		resp.Vehicles = append(resp.Vehicles, *v)
+		if v.Crossings > 0 {
+			waits = append(waits, v.AvgWaitSec)
+		}
+	}
+	sort.Slice(resp.Vehicles, func(i, j int) bool { return resp.Vehicles[i].VehicleID < resp.Vehicles[j].VehicleID })
+
+	resp.VehiclesConsidered = len(waits)
+	resp.JainIndex = jainIndex(waits)
+	return resp
+}
+
+// Manejador HTTP que devuelve los indicadores de equidad entre direcciones y entre vehículos.
+func getFairnessHandler(w http.ResponseWriter, r *http.Request) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	respondWithJSON(w, http.StatusOK, buildFairness(time.Now()))
+}
@@ -1,10 +1,10 @@
-module server
+module github.com/Moringa07/Puente-De-Una-Via/Backend/Server
 
 go 1.23.4
 
-require github.com/gorilla/mux v1.8.1
-
 require (
-	github.com/felixge/httpsnoop v1.0.3 // indirect
-	github.com/gorilla/handlers v1.5.2 // indirect
+	github.com/gorilla/handlers v1.5.2
+	github.com/gorilla/mux v1.8.1
 )
+
+require github.com/felixge/httpsnoop v1.0.3 // indirect
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math"
+	"strconv"
+)
+
+// Límites superiores (en segundos) de los intervalos de los histogramas de tiempos.
+// Un último intervalo implícito recoge los valores mayores que el último límite.
+var latencyBuckets = []float64{0.5, 1, 2, 3, 5, 7.5, 10, 15, 20, 30, 45, 60, 90, 120, 180, 300, 600}
+
+// Histograma de intervalos fijos. Ocupa lo mismo con diez muestras que con un millón.
+type histogram struct {
+	Counts []uint64 `json:"counts"`
+	Count  uint64   `json:"count"`
+	Sum    float64  `json:"sum"`
+	Max    float64  `json:"max"`
+}
+
+// Añade una muestra, en segundos, al histograma.
+func (h *histogram) observe(v float64) {
+	if len(h.Counts) != len(latencyBuckets)+1 {
+		h.Counts = make([]uint64, len(latencyBuckets)+1)
+	}
+	i := len(latencyBuckets)
+	for j, bound := range latencyBuckets {
+		if v <= bound {
+			i = j
+			break
+		}
+	}
+	h.Counts[i]++
+	h.Count++
+	h.Sum += v
+	h.Max = max(h.Max, v)
+}
+
+// Estima el cuantil q (entre 0 y 1) interpolando linealmente dentro del intervalo que lo contiene.
+func (h histogram) quantile(q float64) float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := q * float64(h.Count)
+	var cumulative float64
+	for i, count := range h.Counts {
+		if count == 0 {
+			continue
+		}
+		if cumulative+float64(count) >= rank {
+			lower := 0.0
+			if i > 0 {
+				lower = latencyBuckets[i-1]
+			}
+			// El último intervalo no tiene límite: se usa el máximo observado.
+			upper := h.Max
+			if i < len(latencyBuckets) {
+				upper = math.Min(latencyBuckets[i], h.Max)
+			}
+			fraction := (rank - cumulative) / float64(count)
+			return lower + (upper-lower)*fraction
+		}
+		cumulative += float64(count)
+	}
+	return h.Max
+}
+
+// Copia el histograma para poder leerlo fuera del mutex.
+func (h histogram) clone() histogram {
+	h.Counts = append([]uint64(nil), h.Counts...)
+	return h
+}
+
+// Suma las muestras de otro histograma a este.
+func (h *histogram) merge(other histogram) {
+	if len(other.Counts) == 0 {
+		return
+	}
+	if len(h.Counts) != len(other.Counts) {
+		h.Counts = make([]uint64, len(other.Counts))
+	}
+	for i, count := range other.Counts {
+		h.Counts[i] += count
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+	h.Max = max(h.Max, other.Max)
+}
+
+// Prepara el resumen del histograma para la API.
+func (h histogram) response() HistogramResponse {
+	resp := HistogramResponse{
+		Count:   h.Count,
+		SumSec:  h.Sum,
+		MaxSec:  h.Max,
+		P50Sec:  h.quantile(0.50),
+		P90Sec:  h.quantile(0.90),
+		P95Sec:  h.quantile(0.95),
+		P99Sec:  h.quantile(0.99),
+		Buckets: make([]HistogramBucket, 0, len(latencyBuckets)+1),
+	}
+	for i := 0; i <= len(latencyBuckets); i++ {
+		bucket := HistogramBucket{UpperBound: "+Inf"}
+		if i < len(latencyBuckets) {
+			bucket.UpperBound = strconv.FormatFloat(latencyBuckets[i], 'f', -1, 64)
+		}
+		if i < len(h.Counts) {
+			bucket.Count = h.Counts[i]
+		}
+		resp.Buckets = append(resp.Buckets, bucket)
+	}
+	return resp
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Número máximo de cruces que se guardan por vehículo; al superarlo se descartan los más antiguos.
+const maxHistoryPerCar = 1000
+
+// Tamaño de página por defecto y máximo del historial de cruces.
+const (
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 500
+)
+
+// Historial de cruces por ID de vehículo. Se guarda aparte de allCars para que sobreviva a la baja del coche.
+var crossingHistory = make(map[int][]CrossingRecord)
+
+// Añade un cruce al historial del vehículo. Debe llamarse con el mutex bloqueado.
+func recordCrossing(record CrossingRecord) {
+	history := append(crossingHistory[record.CarID], record)
+	if len(history) > maxHistoryPerCar {
+		history = history[len(history)-maxHistoryPerCar:]
+	}
+	crossingHistory[record.CarID] = history
+	journalCrossing(record)
+}
+
+// Manejador HTTP que devuelve, paginado y en orden cronológico, el historial de cruces de un vehículo.
+func getVehicleHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de vehículo inválido")
+		return
+	}
+
+	page, pageSize, ok := parsePagination(r, defaultHistoryPageSize, maxHistoryPageSize)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Parámetros de paginación inválidos")
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	history, hasHistory := crossingHistory[id]
+	if _, exists := allCars[id]; !exists && !hasHistory {
+		respondWithError(w, http.StatusNotFound, "Vehículo no encontrado")
+		return
+	}
+
+	start := min((page-1)*pageSize, len(history))
+	end := min(start+pageSize, len(history))
+
+	// Se copia la página para no exponer el slice compartido fuera del mutex.
+	crossings := make([]CrossingRecord, end-start)
+	copy(crossings, history[start:end])
+
+	respondWithJSON(w, http.StatusOK, CrossingHistoryResponse{
+		VehicleID: id,
+		Total:     len(history),
+		Page:      page,
+		PageSize:  pageSize,
+		Crossings: crossings,
+	})
+}
+
+// Página más alta que se acepta. Con el mayor page_size, el desplazamiento (page-1)*page_size cabe holgado en un int.
+const maxPage = 1_000_000
+
+// Lee los parámetros page y page_size de la URL, aplicando los valores por defecto y el máximo permitido.
+func parsePagination(r *http.Request, defaultSize, maxSize int) (page, pageSize int, ok bool) {
+	page, pageSize = 1, defaultSize
+	query := r.URL.Query()
+
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPage {
+			return 0, 0, false
+		}
+		page = n
+	}
+	if v := query.Get("page_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, false
+		}
+		pageSize = min(n, maxSize)
+	}
+	return page, pageSize, true
+}
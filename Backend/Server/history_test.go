@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		page     int
+		pageSize int
+		ok       bool
+	}{
+		{name: "valores por defecto", query: "", page: 1, pageSize: 20, ok: true},
+		{name: "página y tamaño", query: "page=3&page_size=10", page: 3, pageSize: 10, ok: true},
+		{name: "tamaño por encima del máximo", query: "page_size=1000", page: 1, pageSize: 100, ok: true},
+		{name: "última página permitida", query: "page=" + strconv.Itoa(maxPage), page: maxPage, pageSize: 20, ok: true},
+		{name: "página por encima del máximo", query: "page=" + strconv.Itoa(maxPage+1)},
+		{name: "página que desborda el desplazamiento", query: "page=9223372036854775807"},
+		{name: "página fuera de rango de int", query: "page=99999999999999999999"},
+		{name: "página cero", query: "page=0"},
+		{name: "página negativa", query: "page=-1"},
+		{name: "página no numérica", query: "page=abc"},
+		{name: "tamaño cero", query: "page_size=0"},
+		{name: "tamaño no numérico", query: "page_size=x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/vehicle/1/history?"+tt.query, nil)
+			page, pageSize, ok := parsePagination(r, 20, 100)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, se esperaba %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if page != tt.page || pageSize != tt.pageSize {
+				t.Errorf("page, pageSize = %d, %d; se esperaba %d, %d", page, pageSize, tt.page, tt.pageSize)
+			}
+		})
+	}
+}
+
+// Pide una página del historial por la ruta real, con el token del vehículo.
+func getHistoryPage(t *testing.T, router http.Handler, token, query string) (int, CrossingHistoryResponse) {
+	t.Helper()
+	w := serveRoute(router, "GET", "/api/vehicle/1/history?"+query, token, "")
+	var resp CrossingHistoryResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("respuesta no es JSON: %s", w.Body)
+		}
+	}
+	return w.Code, resp
+}
+
+func TestVehicleHistoryPages(t *testing.T) {
+	freshSimulation(t)
+	mutex.Lock()
+	bridgeClosed = true
+	mutex.Unlock()
+	router := newRouter(false)
+
+	w := serveRoute(router, "POST", "/api/register", "", `{"uuid":"hist","direction":"SUR","speed":5}`)
+	var reg api.RegisterResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &reg) != nil {
+		t.Fatalf("registro: %d %s", w.Code, w.Body)
+	}
+
+	start := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	mutex.Lock()
+	for i := range 7 {
+		crossingHistory[1] = append(crossingHistory[1], CrossingRecord{CarID: 1, Direction: "SUR", GrantedAt: start.Add(time.Duration(i) * time.Minute)})
+	}
+	mutex.Unlock()
+
+	code, page := getHistoryPage(t, router, reg.Token, "page=3&page_size=3")
+	if code != http.StatusOK || page.Total != 7 || page.Page != 3 || len(page.Crossings) != 1 {
+		t.Fatalf("página 3: código %d, %+v", code, page)
+	}
+	if !page.Crossings[0].GrantedAt.Equal(start.Add(6 * time.Minute)) {
+		t.Errorf("la última página trae %v, se esperaba el séptimo cruce", page.Crossings[0].GrantedAt)
+	}
+	if _, page := getHistoryPage(t, router, reg.Token, ""); page.PageSize != defaultHistoryPageSize || len(page.Crossings) != 7 {
+		t.Errorf("página por defecto: %+v", page)
+	}
+	if _, page := getHistoryPage(t, router, reg.Token, "page=4&page_size=3"); page.Total != 7 || len(page.Crossings) != 0 {
+		t.Errorf("página más allá del final: %+v", page)
+	}
+	if code, _ := getHistoryPage(t, router, reg.Token, "page="+strconv.Itoa(maxPage+1)); code != http.StatusBadRequest {
+		t.Errorf("página por encima del máximo: código %d, se esperaba 400", code)
+	}
+
+	// El historial sobrevive a la baja del vehículo mientras se conserve su token.
+	mutex.Lock()
+	delete(allCars, 1)
+	queueSouth = nil
+	mutex.Unlock()
+	if code, page := getHistoryPage(t, router, reg.Token, "page_size=500"); code != http.StatusOK || len(page.Crossings) != 7 {
+		t.Errorf("historial tras la baja: código %d, %d cruces", code, len(page.Crossings))
+	}
+}
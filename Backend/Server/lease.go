@@ -0,0 +1,136 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Arrendamiento del puente concedido a un coche que debe avisar de su salida.
+type bridgeLease struct {
+	carID     int
+	expiresAt time.Time
+	// Se cierra cuando el cliente informa que salió del puente.
+	exited chan struct{}
+}
+
+// Registra el arrendamiento del puente para el coche antes de entregarle el permiso.
+func startLease(car Car) *bridgeLease {
+	lease := &bridgeLease{
+		carID:     car.ID,
+		expiresAt: time.Now().Add(leaseTimeout),
+		exited:    make(chan struct{}),
+	}
+
+	mutex.Lock()
+	activeLease = lease
+	if c, exists := allCars[car.ID]; exists {
+		c.LeaseExpiresAt = lease.expiresAt.Unix()
+		allCars[car.ID] = c
+	}
+	mutex.Unlock()
+
+	return lease
+}
+
+// Bloquea el puente hasta que el cliente avisa de su salida. Devuelve false si el plazo venció antes
+// o si se ordenó evacuar el puente.
+func waitForExit(lease *bridgeLease, evacuate <-chan struct{}) bool {
+	log.Printf("[Auto %d] Cruzando en modo arrendamiento. Debe avisar su salida antes de %v.", lease.carID, leaseTimeout)
+
+	timer := time.NewTimer(time.Until(lease.expiresAt))
+	defer timer.Stop()
+
+	select {
+	case <-lease.exited:
+		return true
+	case <-timer.C:
+	case <-evacuate:
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// El aviso pudo llegar justo cuando vencía el plazo.
+	select {
+	case <-lease.exited:
+		return true
+	default:
+	}
+	if activeLease == lease {
+		activeLease = nil
+	}
+	return false
+}
+
+// Registra el aviso de salida del coche que tiene el puente arrendado.
+func reportExit(carID int) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if activeLease == nil || activeLease.carID != carID {
+		return false
+	}
+	close(activeLease.exited)
+	activeLease = nil
+	log.Printf("[Auto %d] Informó su salida del puente.", carID)
+	return true
+}
+
+// Revoca el permiso de un coche que no avisó su salida a tiempo, lo marca como defectuoso y libera el puente.
+func revokeLease(car Car) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !holdsBridge(car.ID) {
+		return
+	}
+	log.Printf("[Auto %d] El arrendamiento venció sin aviso de salida. Permiso revocado y cliente marcado como defectuoso.", car.ID)
+
+	faultyClients[car.UUID] = true
+	if c, exists := allCars[car.ID]; exists {
+		c.Status = "faulty"
+		c.Faulty = true
+		c.IsLooping = false
+		c.LeaseExpiresAt = 0
+		c.Stats.RevokedLeases++
+		allCars[c.ID] = c
+	}
+	noteBridgeRelease(time.Now())
+	bridgeStats.RevokedLeases++
+
+	notifyCar(car.ID, serverMessage{
+		Type:    msgRevoked,
+		ID:      car.ID,
+		Code:    "lease_expired",
+		Message: "El plazo para salir del puente venció",
+	})
+	finishSessionCrossing(car.ID)
+
+	bridgeBusy = false
+	currentCar = nil
+	journalChange(car.ID)
+	revoked := carEvent(evRevoked, car)
+	revoked.Reason = "lease_expired"
+	recordEvent(revoked)
+	go processQueue()
+}
+
+// Manejador HTTP con el que un vehículo en modo arrendamiento informa que salió del puente.
+func exitVehicleHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de vehículo inválido")
+		return
+	}
+
+	if !reportExit(id) {
+		respondWithError(w, http.StatusConflict, "El vehículo no tiene el puente arrendado")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
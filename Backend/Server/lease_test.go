@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Abre una sesión v2 y registra un coche en modo arrendamiento hacia el norte. Devuelve el permiso recibido.
+func leaseCrossing(t *testing.T, uuid string) (*testClient, serverMessage) {
+	t.Helper()
+	c := dialTestClient(t)
+	c.send(clientMessage{Type: msgHello, Version: 2})
+	c.expect(msgHello)
+
+	// Sin cruce en curso no hay arrendamiento que terminar.
+	c.send(clientMessage{Type: msgExited})
+	if e := c.expect(msgError); e.Code != "no_lease" {
+		t.Fatalf("exited sin arrendamiento: código %q, se esperaba no_lease", e.Code)
+	}
+
+	c.send(clientMessage{Type: msgRegister, UUID: uuid, Direction: "NORTE", Speed: 5, Lease: true})
+	c.expect(msgRegistered)
+	return c, c.expect(msgGranted)
+}
+
+func TestLeaseCrossingEndsOnExit(t *testing.T) {
+	freshSimulation(t)
+
+	c, granted := leaseCrossing(t, "lease-ok")
+	if granted.LeaseSec != leaseTimeout.Seconds() {
+		t.Errorf("lease_sec = %v, se esperaba %v", granted.LeaseSec, leaseTimeout.Seconds())
+	}
+	c.send(clientMessage{Type: msgExited})
+	finished := c.expect(msgFinished)
+	if finished.ID != granted.ID || finished.DurationSec <= 0 || finished.DurationSec >= leaseTimeout.Seconds() {
+		t.Errorf("finished = %+v", finished)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if bridgeBusy || activeLease != nil {
+		t.Errorf("puente ocupado=%v arrendamiento=%v tras el aviso de salida", bridgeBusy, activeLease)
+	}
+	if n := bridgeStats.direction("NORTE").Crossings; n != 1 {
+		t.Errorf("cruces al norte = %d, se esperaba 1", n)
+	}
+	if h := crossingHistory[granted.ID]; len(h) != 1 || h[0].DurationSec != finished.DurationSec {
+		t.Errorf("historial = %+v", h)
+	}
+}
+
+func TestLeaseExpiryRevokesAndMarksFaulty(t *testing.T) {
+	freshSimulation(t)
+	mutex.Lock()
+	leaseTimeout = 50 * time.Millisecond
+	mutex.Unlock()
+
+	c, granted := leaseCrossing(t, "lease-late")
+	if revoked := c.expect(msgRevoked); revoked.Code != "lease_expired" {
+		t.Fatalf("revoked = %+v, se esperaba el código lease_expired", revoked)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	car := allCars[granted.ID]
+	if car.Status != "faulty" || !car.Faulty || car.Stats.RevokedLeases != 1 || !faultyClients["lease-late"] {
+		t.Errorf("coche %+v, cliente defectuoso=%v", car.Car, faultyClients["lease-late"])
+	}
+	if bridgeBusy || bridgeStats.RevokedLeases != 1 || bridgeStats.direction("NORTE").Crossings != 0 {
+		t.Errorf("puente ocupado=%v revocados=%d cruces=%d", bridgeBusy, bridgeStats.RevokedLeases, bridgeStats.direction("NORTE").Crossings)
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Modos de cierre del puente.
+const (
+	// Deja terminar al coche que cruza; las colas se conservan y nadie más recibe el paso.
+	closeDrain = "drain"
+	// Evacúa al coche que cruza: su cruce no cuenta y vuelve al frente de su cola.
+	closeImmediate = "immediate"
+)
+
+// Cada cuánto se revisan los cierres programados y las reaperturas previstas.
+const closureCheckInterval = time.Second
+
+// Variables de los cierres del puente.
+var (
+	// Indica si el puente está cerrado. Protegido por el mutex global.
+	bridgeClosed bool
+	// Modo, motivo y reapertura prevista del cierre en curso; solo vale con bridgeClosed. Protegido por el mutex global.
+	bridgeClosure BridgeClosure
+	// Cierres programados pendientes, por orden de inicio. Protegidos por el mutex global.
+	closureSchedule []ScheduledClosure
+	// Último ID asignado a un cierre programado. Protegido por el mutex global.
+	scheduleCounter int
+	// Se cierra para evacuar al coche que cruza; pertenece al cruce en curso. Protegido por el mutex global.
+	evacuationCh chan struct{}
+)
+
+// Indica si el modo de cierre es válido.
+func validCloseMode(mode string) bool {
+	return mode == closeDrain || mode == closeImmediate
+}
+
+// Cierra el puente. En modo inmediato evacúa al coche que cruza. Debe llamarse con el mutex bloqueado.
+func closeBridge(closure BridgeClosure) {
+	bridgeClosed = true
+	bridgeClosure = closure
+	if closure.Mode == closeImmediate && bridgeBusy && evacuationCh != nil {
+		close(evacuationCh)
+		evacuationCh = nil
+	}
+	journalChange()
+	recordEvent(bridgeEvent{Type: evBridgeClosed, Reason: closure.Mode})
+
+	reopen := "hasta que se reabra"
+	if closure.ReopenAt != nil {
+		reopen = "hasta las " + closure.ReopenAt.Local().Format("15:04:05")
+	}
+	log.Printf("[Puente] Cerrado (%s) %s. Motivo: %q. Los vehículos en cola esperarán.", closure.Mode, reopen, closure.Reason)
+}
+
+// Reabre el puente y da paso al siguiente coche en cola. reason queda en el evento. Debe llamarse con el mutex bloqueado.
+func openBridge(reason string) {
+	bridgeClosed = false
+	bridgeClosure = BridgeClosure{}
+	journalChange()
+	recordEvent(bridgeEvent{Type: evBridgeOpened, Reason: reason})
+	go processQueue()
+
+	log.Println("[Puente] Reabierto.")
+}
+
+// Devuelve una copia del cierre en curso, o nil si el puente está abierto. Debe llamarse con el mutex bloqueado.
+func currentClosure() *BridgeClosure {
+	if !bridgeClosed {
+		return nil
+	}
+	closure := bridgeClosure
+	return &closure
+}
+
+// Prepara el aviso de evacuación del cruce que empieza. Debe llamarse con el mutex bloqueado, al asignar currentCar.
+func newEvacuation() <-chan struct{} {
+	evacuationCh = make(chan struct{})
+	return evacuationCh
+}
+
+// Indica si se ordenó evacuar el cruce.
+func evacuated(evacuate <-chan struct{}) bool {
+	select {
+	case <-evacuate:
+		return true
+	default:
+		return false
+	}
+}
+
+// Espera y adelantamientos sufridos con los que el coche recibió el paso, que recupera si el cruce se evacúa.
+type grantRecord struct {
+	wait      time.Duration
+	overtaken int
+}
+
+// Saca del puente al coche que cruzaba cuando se cerró de inmediato y lo devuelve al frente de su cola.
+// Las estadísticas del puente conservan el paso, que se suma a las evacuaciones. En las del vehículo el paso
+// se deshace y su espera sigue contando desde su llegada original a la cola.
+func evacuateCrossing(car Car, grant grantRecord) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// Un reinicio de la simulación también despierta el cruce, pero ya liberó el puente y retiró al coche.
+	if !holdsBridge(car.ID) {
+		return
+	}
+	log.Printf("[Auto %d] Evacuado del puente por un cierre inmediato. Vuelve al frente de la cola %s.", car.ID, car.Direction)
+
+	now := time.Now()
+	if activeLease != nil && activeLease.carID == car.ID {
+		activeLease = nil
+	}
+	noteBridgeRelease(now)
+	bridgeStats.EvacuatedCrossings++
+	bridgeBusy = false
+	currentCar = nil
+	evacuatedEv := carEvent(evEvacuated, car)
+	evacuatedEv.WaitSec = grant.wait.Seconds()
+	recordEvent(evacuatedEv)
+
+	c, exists := allCars[car.ID]
+	if !exists {
+		journalChange()
+		return
+	}
+	undoVehicleGrant(&c.Stats, car.Direction, grant.wait)
+	c.Status = "waiting"
+	c.LeaseExpiresAt = 0
+	c.TimeEnteredQueue = c.TimeStartedCross.Add(-grant.wait)
+	c.OvertakenInQueue += grant.overtaken
+	c.EvacuatedAt = now
+	allCars[c.ID] = c
+
+	notifyCar(car.ID, serverMessage{
+		Type:    msgRevoked,
+		ID:      car.ID,
+		Code:    "evacuated",
+		Message: "El puente se cerró de inmediato. El vehículo vuelve al frente de su cola",
+	})
+	if c.Direction == "NORTE" {
+		queueNorth = append([]Car{c}, queueNorth...)
+	} else {
+		queueSouth = append([]Car{c}, queueSouth...)
+	}
+	noteQueueChange(now)
+	notifyQueuePositions()
+	journalChange(car.ID)
+}
+
+// Revisa cada segundo las reaperturas previstas y los cierres programados.
+func runClosureSchedule() {
+	ticker := time.NewTicker(closureCheckInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		mutex.Lock()
+		applyClosureSchedule(now)
+		mutex.Unlock()
+	}
+}
+
+// Reabre el puente si llegó su reapertura prevista y empieza los cierres programados cuyo inicio llegó.
+// Debe llamarse con el mutex bloqueado.
+func applyClosureSchedule(now time.Time) {
+	if bridgeClosed && bridgeClosure.ReopenAt != nil && !now.Before(*bridgeClosure.ReopenAt) {
+		log.Println("[Puente] Llegó la hora prevista de reapertura.")
+		openBridge("scheduled")
+	}
+
+	for len(closureSchedule) > 0 && !now.Before(closureSchedule[0].StartAt) {
+		scheduled := closureSchedule[0]
+		closureSchedule = closureSchedule[1:]
+		end := scheduled.EndAt
+
+		switch {
+		case !now.Before(end):
+			log.Printf("[Puente] El cierre programado %d terminó mientras el servidor estaba detenido. Descartado.", scheduled.ID)
+		case bridgeClosed:
+			// Un cierre en curso con fin previsto se prolonga hasta el fin del programado; uno indefinido sigue igual.
+			if bridgeClosure.ReopenAt != nil && bridgeClosure.ReopenAt.Before(end) {
+				bridgeClosure.ReopenAt = &end
+				bridgeClosure.ScheduleID = scheduled.ID
+			}
+			log.Printf("[Puente] El cierre programado %d empezó con el puente ya cerrado.", scheduled.ID)
+		default:
+			closeBridge(BridgeClosure{
+				Mode:       scheduled.Mode,
+				Reason:     scheduled.Reason,
+				Since:      now,
+				ReopenAt:   &end,
+				ScheduleID: scheduled.ID,
+			})
+		}
+		journalChange()
+	}
+}
+
+// Manejador HTTP que cierra el puente. El cuerpo es opcional: modo, motivo y hora de reapertura automática.
+func closeBridgeHandler(w http.ResponseWriter, r *http.Request) {
+	var req api.CloseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Formato inválido")
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = closeDrain
+	}
+	if !validCloseMode(req.Mode) {
+		respondWithError(w, http.StatusBadRequest, "'mode' debe ser drain o immediate")
+		return
+	}
+	now := time.Now()
+	if req.ReopenAt != nil && !req.ReopenAt.After(now) {
+		respondWithError(w, http.StatusBadRequest, "'reopen_at' debe ser una hora futura")
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if bridgeClosed {
+		respondWithError(w, http.StatusConflict, "El puente ya está cerrado")
+		return
+	}
+	message := "Puente cerrado."
+	if currentCar != nil {
+		message = "Puente cerrado. El vehículo que cruza terminará su cruce."
+		if req.Mode == closeImmediate {
+			message = fmt.Sprintf("Puente cerrado de inmediato. El vehículo %d vuelve al frente de su cola.", currentCar.ID)
+		}
+	}
+	closeBridge(BridgeClosure{Mode: req.Mode, Reason: req.Reason, Since: now, ReopenAt: req.ReopenAt})
+
+	auditDetail(r, "%s: %s", req.Mode, req.Reason)
+	respondWithJSON(w, http.StatusOK, api.MessageResponse{Message: message})
+}
+
+// Manejador HTTP que reabre el puente y da paso al siguiente coche en cola.
+func openBridgeHandler(w http.ResponseWriter, r *http.Request) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !bridgeClosed {
+		respondWithError(w, http.StatusConflict, "El puente ya está abierto")
+		return
+	}
+	openBridge("")
+	respondWithJSON(w, http.StatusOK, api.MessageResponse{Message: "Puente abierto."})
+}
+
+// Manejador HTTP que devuelve los cierres programados pendientes.
+func getClosureScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	closures := make([]ScheduledClosure, len(closureSchedule))
+	copy(closures, closureSchedule)
+	respondWithJSON(w, http.StatusOK, api.ClosureScheduleResponse{Closures: closures})
+}
+
+// Manejador HTTP que programa un cierre del puente entre dos horas. No puede superponerse con otro pendiente.
+func scheduleClosureHandler(w http.ResponseWriter, r *http.Request) {
+	var req api.ScheduleClosureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Formato inválido")
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = closeDrain
+	}
+	switch {
+	case !validCloseMode(req.Mode):
+		respondWithError(w, http.StatusBadRequest, "'mode' debe ser drain o immediate")
+		return
+	case req.StartAt.IsZero() || req.EndAt.IsZero():
+		respondWithError(w, http.StatusBadRequest, "Indique 'start_at' y 'end_at'")
+		return
+	case !req.EndAt.After(req.StartAt):
+		respondWithError(w, http.StatusBadRequest, "'end_at' debe ser posterior a 'start_at'")
+		return
+	case !req.EndAt.After(time.Now()):
+		respondWithError(w, http.StatusBadRequest, "'end_at' debe ser una hora futura")
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, other := range closureSchedule {
+		if req.StartAt.Before(other.EndAt) && other.StartAt.Before(req.EndAt) {
+			respondWithError(w, http.StatusConflict, fmt.Sprintf("Se superpone con el cierre programado %d", other.ID))
+			return
+		}
+	}
+
+	scheduleCounter++
+	scheduled := ScheduledClosure{
+		ID:      scheduleCounter,
+		Mode:    req.Mode,
+		Reason:  req.Reason,
+		StartAt: req.StartAt,
+		EndAt:   req.EndAt,
+	}
+	closureSchedule = append(closureSchedule, scheduled)
+	sort.Slice(closureSchedule, func(i, j int) bool { return closureSchedule[i].StartAt.Before(closureSchedule[j].StartAt) })
+	journalChange()
+
+	auditDetail(r, "cierre %d (%s) de %s a %s: %s", scheduled.ID, scheduled.Mode, scheduled.StartAt.Format(time.RFC3339), scheduled.EndAt.Format(time.RFC3339), scheduled.Reason)
+	log.Printf("[Puente] Cierre %d programado de %s a %s.", scheduled.ID, scheduled.StartAt.Local().Format(time.DateTime), scheduled.EndAt.Local().Format(time.DateTime))
+	respondWithJSON(w, http.StatusOK, scheduled)
+}
+
+// Manejador HTTP que cancela un cierre programado que aún no empezó.
+func cancelScheduledClosureHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de cierre inválido")
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for i, scheduled := range closureSchedule {
+		if scheduled.ID == id {
+			closureSchedule = append(closureSchedule[:i], closureSchedule[i+1:]...)
+			journalChange()
+			auditDetail(r, "cierre %d", id)
+			log.Printf("[Puente] Cierre programado %d cancelado.", id)
+			respondWithJSON(w, http.StatusOK, api.MessageResponse{Message: fmt.Sprintf("Cierre programado %d cancelado.", id)})
+			return
+		}
+	}
+	if bridgeClosed && bridgeClosure.ScheduleID == id {
+		respondWithError(w, http.StatusConflict, "El cierre programado ya empezó. Reabra el puente con /api/bridge/open")
+		return
+	}
+	respondWithError(w, http.StatusNotFound, "Cierre programado no encontrado")
+}
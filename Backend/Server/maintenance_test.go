@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Un cierre inmediato saca al coche del puente y lo devuelve al frente de su cola. El puente conserva el paso
+// evacuado y, al reabrir, suma solo la espera nueva; el vehículo cuenta un único paso con su espera desde la llegada.
+func TestImmediateCloseEvacuatesCrossing(t *testing.T) {
+	freshSimulation(t)
+	prevKey := adminKey
+	adminKey = "k"
+	t.Cleanup(func() { adminKey = prevKey })
+	router := newRouter(false)
+
+	// Un arrendamiento deja al coche en el puente hasta que lo saque el cierre.
+	c, granted := leaseCrossing(t, "evacuado")
+	id := granted.ID
+	time.Sleep(100 * time.Millisecond)
+
+	if w := serveRoute(router, "POST", "/api/bridge/close", "k", `{"mode":"immediate","reason":"prueba"}`); w.Code != http.StatusOK {
+		t.Fatalf("cierre inmediato: código %d %s", w.Code, w.Body)
+	}
+	if revoked := c.expect(msgRevoked); revoked.Code != "evacuated" {
+		t.Fatalf("revoked = %+v, se esperaba el código evacuated", revoked)
+	}
+
+	mutex.Lock()
+	car := allCars[id]
+	north := bridgeStats.direction("NORTE")
+	if bridgeBusy || currentCar != nil || activeLease != nil {
+		t.Errorf("puente ocupado=%v coche=%v arrendamiento=%v tras la evacuación", bridgeBusy, currentCar, activeLease)
+	}
+	if len(queueNorth) != 1 || queueNorth[0].ID != id || car.Status != "waiting" || car.EvacuatedAt.IsZero() {
+		t.Errorf("cola norte=%v estado=%q evacuado=%v", carIDs(queueNorth), car.Status, car.EvacuatedAt)
+	}
+	if bridgeStats.EvacuatedCrossings != 1 || north.Grants != 1 || north.WaitHist.Count != 1 || north.Crossings != 0 {
+		t.Errorf("evacuados=%d permisos=%d histograma=%d cruces=%d", bridgeStats.EvacuatedCrossings, north.Grants, north.WaitHist.Count, north.Crossings)
+	}
+	if car.Stats.North.Grants != 0 || car.Stats.North.TotalWait != 0 {
+		t.Errorf("el vehículo conserva el paso evacuado: %+v", car.Stats.North)
+	}
+	firstWait := north.TotalWait
+	mutex.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+	if w := serveRoute(router, "POST", "/api/bridge/open", "k", ""); w.Code != http.StatusOK {
+		t.Fatalf("reapertura: código %d %s", w.Code, w.Body)
+	}
+	regranted := c.expect(msgGranted)
+	// El permiso se envía antes de anotarlo: se espera a que el coche figure cruzando.
+	var again Car
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		mutex.Lock()
+		again = allCars[id]
+		mutex.Unlock()
+		if again.Status == "crossing" || time.Now().After(deadline) {
+			break
+		}
+	}
+	if regranted.ID != id || again.Status != "crossing" || !again.EvacuatedAt.IsZero() {
+		t.Fatalf("nuevo permiso para %d: estado=%q evacuado=%v", regranted.ID, again.Status, again.EvacuatedAt)
+	}
+	// El tiempo en el puente antes del cierre cuenta como espera del vehículo, pero no se suma otra vez al puente.
+	vehicleWait := again.Stats.North.TotalWait
+	if again.Stats.North.Grants != 1 || vehicleWait < 120*time.Millisecond {
+		t.Errorf("permisos del vehículo=%d espera=%v", again.Stats.North.Grants, vehicleWait)
+	}
+
+	c.send(clientMessage{Type: msgExited})
+	finished := c.expect(msgFinished)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	north = bridgeStats.direction("NORTE")
+	if north.Grants != 2 || north.WaitHist.Count != 2 || north.Crossings != 1 || bridgeStats.EvacuatedCrossings != 1 {
+		t.Errorf("permisos=%d histograma=%d cruces=%d evacuados=%d", north.Grants, north.WaitHist.Count, north.Crossings, bridgeStats.EvacuatedCrossings)
+	}
+	if newWait := north.TotalWait - firstWait; newWait < 20*time.Millisecond || newWait > vehicleWait-80*time.Millisecond {
+		t.Errorf("espera nueva sumada al puente=%v; la del vehículo es %v", newWait, vehicleWait)
+	}
+	if h := crossingHistory[id]; len(h) != 1 || h[0].WaitSec != finished.WaitSec || h[0].WaitSec != vehicleWait.Seconds() {
+		t.Errorf("historial=%+v finished=%+v", h, finished)
+	}
+}
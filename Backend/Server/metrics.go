@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Clave de los contadores de peticiones HTTP.
+type httpRequestKey struct {
+	Route  string
+	Method string
+	Status int
+}
+
+// Contadores expuestos en /metrics que no se derivan del estado de la simulación. Protegidos por el mutex global.
+var (
+	// Conexiones TCP abiertas en este momento, con o sin vehículo registrado.
+	activeTCPConns int
+	// Vehículos eliminados por el limpiador desde que arrancó el servidor.
+	cleanupEvictions int
+	// Peticiones HTTP atendidas, por plantilla de ruta, método y código de estado.
+	httpRequests = make(map[httpRequestKey]int)
+)
+
+// Guarda el código de estado que escribe un manejador HTTP.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// Registra el código de estado antes de enviarlo.
+func (rec *statusRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Permite a los manejadores enviar la respuesta por partes a través del registrador.
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Middleware que cuenta las peticiones por plantilla de ruta (p. ej. /api/vehicle/{id}) y código de estado.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		countRequests(routeTemplate(r), next).ServeHTTP(w, r)
+	})
+}
+
+// Devuelve la plantilla de la ruta de la petición, o "unmatched" si no coincide con ninguna.
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tpl, err := current.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
+
+// Envuelve un manejador para contar sus peticiones bajo la ruta indicada.
+func countRequests(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		mutex.Lock()
+		httpRequests[httpRequestKey{Route: route, Method: r.Method, Status: rec.status}]++
+		mutex.Unlock()
+	})
+}
+
+// Cuenta una conexión TCP abierta y devuelve la función que la descuenta al cerrarse.
+func trackTCPConn() func() {
+	mutex.Lock()
+	activeTCPConns++
+	mutex.Unlock()
+
+	return func() {
+		mutex.Lock()
+		activeTCPConns--
+		mutex.Unlock()
+	}
+}
+
+// Manejador HTTP que expone las métricas en el formato de texto de Prometheus.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	mutex.Lock()
+	var b strings.Builder
+	writeMetrics(&b)
+	mutex.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	io.WriteString(w, b.String())
+}
+
+// Escribe todas las métricas del servidor. Debe llamarse con el mutex bloqueado.
+func writeMetrics(w io.Writer) {
+	directions := []string{"NORTE", "SUR"}
+
+	writeMetricHeader(w, "puente_queue_length", "gauge", "Vehículos esperando en la cola de cada dirección.")
+	writeSample(w, "puente_queue_length", labels("direction", "NORTE"), float64(len(queueNorth)))
+	writeSample(w, "puente_queue_length", labels("direction", "SUR"), float64(len(queueSouth)))
+
+	busy := 0.0
+	if bridgeBusy {
+		busy = 1
+	}
+	writeMetricHeader(w, "puente_bridge_busy", "gauge", "1 si hay un vehículo cruzando el puente, 0 si está libre.")
+	writeSample(w, "puente_bridge_busy", "", busy)
+
+	writeMetricHeader(w, "puente_registered_cars", "gauge", "Vehículos registrados en el servidor.")
+	writeSample(w, "puente_registered_cars", "", float64(len(allCars)))
+
+	writeMetricHeader(w, "puente_tcp_connections", "gauge", "Conexiones TCP abiertas.")
+	writeSample(w, "puente_tcp_connections", "", float64(activeTCPConns))
+
+	writeMetricHeader(w, "puente_cleanup_evictions_total", "counter", "Vehículos eliminados por inactividad por el limpiador.")
+	writeSample(w, "puente_cleanup_evictions_total", "", float64(cleanupEvictions))
+
+	writeMetricHeader(w, "puente_crossings_total", "counter", "Cruces completados por dirección.")
+	for _, dir := range directions {
+		writeSample(w, "puente_crossings_total", labels("direction", dir), float64(bridgeStats.direction(dir).Crossings))
+	}
+
+	writeMetricHeader(w, "puente_abandoned_crossings_total", "counter", "Cruces abandonados porque el cliente ya no estaba conectado.")
+	writeSample(w, "puente_abandoned_crossings_total", "", float64(bridgeStats.AbandonedCrossings))
+
+	writeMetricHeader(w, "puente_revoked_leases_total", "counter", "Arrendamientos revocados por vencer sin aviso de salida.")
+	writeSample(w, "puente_revoked_leases_total", "", float64(bridgeStats.RevokedLeases))
+
+	writeMetricHeader(w, "puente_evacuated_crossings_total", "counter", "Cruces interrumpidos por un cierre inmediato del puente.")
+	writeSample(w, "puente_evacuated_crossings_total", "", float64(bridgeStats.EvacuatedCrossings))
+
+	writeMetricHeader(w, "puente_wait_seconds", "histogram", "Espera en cola antes de entrar al puente.")
+	for _, dir := range directions {
+		writeHistogram(w, "puente_wait_seconds", "direction", dir, bridgeStats.direction(dir).WaitHist)
+	}
+
+	writeMetricHeader(w, "puente_crossing_seconds", "histogram", "Duración de los cruces.")
+	for _, dir := range directions {
+		writeHistogram(w, "puente_crossing_seconds", "direction", dir, bridgeStats.direction(dir).CrossHist)
+	}
+
+	keys := make([]httpRequestKey, 0, len(httpRequests))
+	for key := range httpRequests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Route != keys[j].Route {
+			return keys[i].Route < keys[j].Route
+		}
+		if keys[i].Method != keys[j].Method {
+			return keys[i].Method < keys[j].Method
+		}
+		return keys[i].Status < keys[j].Status
+	})
+	writeMetricHeader(w, "puente_http_requests_total", "counter", "Peticiones HTTP atendidas por ruta, método y código de estado.")
+	for _, key := range keys {
+		lbl := labels("route", key.Route, "method", key.Method, "status", strconv.Itoa(key.Status))
+		writeSample(w, "puente_http_requests_total", lbl, float64(httpRequests[key]))
+	}
+}
+
+// Escribe las líneas HELP y TYPE de una métrica.
+func writeMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Escribe una muestra de la métrica con sus etiquetas ya formateadas.
+func writeSample(w io.Writer, name, labels string, value float64) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// Escribe un histograma con intervalos acumulados, su suma y su conteo.
+func writeHistogram(w io.Writer, name, labelName, labelValue string, h histogram) {
+	var cumulative uint64
+	for i, bound := range latencyBuckets {
+		if i < len(h.Counts) {
+			cumulative += h.Counts[i]
+		}
+		le := strconv.FormatFloat(bound, 'g', -1, 64)
+		writeSample(w, name+"_bucket", labels(labelName, labelValue, "le", le), float64(cumulative))
+	}
+	writeSample(w, name+"_bucket", labels(labelName, labelValue, "le", "+Inf"), float64(h.Count))
+	writeSample(w, name+"_sum", labels(labelName, labelValue), h.Sum)
+	writeSample(w, name+"_count", labels(labelName, labelValue), float64(h.Count))
+}
+
+// Formatea pares nombre/valor como etiquetas de Prometheus, escapando los valores.
+func labels(pairs ...string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], escaper.Replace(pairs[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Pide /metrics al enrutador y devuelve cada muestra por su nombre con etiquetas, tal como aparece en el texto.
+func scrapeMetrics(t *testing.T, router http.Handler) map[string]float64 {
+	t.Helper()
+	w := serveRoute(router, "GET", "/metrics", "", "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("/metrics: código %d, tipo %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	samples := make(map[string]float64)
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if i < 0 || err != nil {
+			t.Fatalf("muestra mal formada: %q", line)
+		}
+		samples[line[:i]] = value
+	}
+	return samples
+}
+
+func TestMetricsReflectSimulation(t *testing.T) {
+	freshSimulation(t)
+	router := newRouter(false)
+
+	now := time.Now()
+	mutex.Lock()
+	httpRequests = make(map[httpRequestKey]int)
+	queueSouth = []Car{{Car: api.Car{ID: 1, Direction: "SUR"}}, {Car: api.Car{ID: 2, Direction: "SUR"}}}
+	allCars[1], allCars[2] = queueSouth[0], queueSouth[1]
+	// Dos cruces al norte, tras esperar 0,2 y 4 segundos; el segundo sigue en el puente.
+	noteBridgeGrant("NORTE", 200*time.Millisecond, now)
+	noteBridgeExit("NORTE", 3*time.Second, now.Add(3*time.Second))
+	noteBridgeGrant("NORTE", 4*time.Second, now.Add(3*time.Second))
+	bridgeBusy = true
+	bridgeStats.EvacuatedCrossings = 1
+	bridgeStats.AbandonedCrossings = 2
+	mutex.Unlock()
+
+	serveRoute(router, "GET", "/api/vehicle/1", "", "")
+	serveRoute(router, "GET", "/api/vehicle/99", "", "")
+	serveRoute(router, "GET", "/no-existe", "", "")
+
+	m := scrapeMetrics(t, router)
+	want := map[string]float64{
+		`puente_queue_length{direction="SUR"}`:                                            2,
+		`puente_queue_length{direction="NORTE"}`:                                          0,
+		`puente_bridge_busy`:                                                              1,
+		`puente_registered_cars`:                                                          2,
+		`puente_crossings_total{direction="NORTE"}`:                                       1,
+		`puente_evacuated_crossings_total`:                                                1,
+		`puente_abandoned_crossings_total`:                                                2,
+		`puente_wait_seconds_bucket{direction="NORTE",le="0.5"}`:                          1,
+		`puente_wait_seconds_bucket{direction="NORTE",le="3"}`:                            1,
+		`puente_wait_seconds_bucket{direction="NORTE",le="5"}`:                            2,
+		`puente_wait_seconds_bucket{direction="NORTE",le="+Inf"}`:                         2,
+		`puente_wait_seconds_count{direction="NORTE"}`:                                    2,
+		`puente_wait_seconds_sum{direction="NORTE"}`:                                      4.2,
+		`puente_wait_seconds_count{direction="SUR"}`:                                      0,
+		`puente_crossing_seconds_bucket{direction="NORTE",le="2"}`:                        0,
+		`puente_crossing_seconds_bucket{direction="NORTE",le="3"}`:                        1,
+		`puente_crossing_seconds_count{direction="NORTE"}`:                                1,
+		`puente_http_requests_total{route="/api/vehicle/{id}",method="GET",status="200"}`: 1,
+		`puente_http_requests_total{route="/api/vehicle/{id}",method="GET",status="404"}`: 1,
+		`puente_http_requests_total{route="unmatched",method="GET",status="404"}`:         1,
+	}
+	for name, value := range want {
+		got, ok := m[name]
+		if !ok {
+			t.Errorf("falta la muestra %s", name)
+		} else if got != value {
+			t.Errorf("%s = %v, se esperaba %v", name, got, value)
+		}
+	}
+
+	// La petición a /metrics se cuenta al terminar, así que aparece en la siguiente lectura.
+	m = scrapeMetrics(t, router)
+	if got := m[`puente_http_requests_total{route="/metrics",method="GET",status="200"}`]; got != 1 {
+		t.Errorf("peticiones a /metrics = %v, se esperaba 1", got)
+	}
+}
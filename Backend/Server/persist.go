@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// Variables de la persistencia del estado. Los cambios se escriben en orden desde una única rutina.
+var (
+	// Ruta de la instantánea del estado; el diario se guarda junto a ella con la extensión .journal.
+	stateFile string
+	// Cada cuánto se escribe una instantánea completa y se vacía el diario.
+	snapshotInterval time.Duration
+	// Cambios pendientes de escribir; es nil si la persistencia está desactivada. Protegido por el mutex global.
+	persistQueue *writeQueue[persistItem]
+	// Se cierra cuando la rutina de escritura terminó de vaciar la cola.
+	persistDone chan struct{}
+)
+
+// Coche tal como se guarda en disco, incluidos los campos que la API no expone.
+type persistedCar struct {
+	Car
+	TimeEnteredQueue time.Time `json:"time_entered_queue"`
+	// Solo está si un cierre inmediato lo sacó del puente y aún no volvió a recibir paso.
+	EvacuatedAt *time.Time `json:"evacuated_at,omitempty"`
+	// Los coches TCP no se restauran porque su conexión no sobrevive al reinicio.
+	TCP bool `json:"tcp,omitempty"`
+}
+
+// Registro de estado. Una instantánea contiene el estado completo, con todo el historial de cruces;
+// una entrada del diario, solo lo que cambió y los cruces nuevos.
+type stateRecord struct {
+	SavedAt      time.Time        `json:"saved_at"`
+	CarCounter   int              `json:"car_counter"`
+	Registry     map[string]int   `json:"registry,omitempty"`
+	Tokens       map[int]string   `json:"tokens,omitempty"`
+	Faulty       []string         `json:"faulty,omitempty"`
+	Cars         []persistedCar   `json:"cars,omitempty"`
+	Deleted      []int            `json:"deleted,omitempty"`
+	Crossings    []CrossingRecord `json:"crossings,omitempty"`
+	QueueNorth   []int            `json:"queue_north"`
+	QueueSouth   []int            `json:"queue_south"`
+	CurrentDir   string           `json:"current_dir"`
+	CurrentCarID int              `json:"current_car_id"`
+	Bridge       *bridgeTotals    `json:"bridge,omitempty"`
+	BridgeClosed bool             `json:"bridge_closed,omitempty"`
+	// Datos del cierre en curso; no está en los archivos anteriores a los modos de cierre.
+	Closure         *BridgeClosure     `json:"closure,omitempty"`
+	Schedule        []ScheduledClosure `json:"schedule,omitempty"`
+	ScheduleCounter int                `json:"schedule_counter,omitempty"`
+	Policy          string             `json:"policy,omitempty"`
+}
+
+// Elemento enviado a la rutina de escritura.
+type persistItem struct {
+	record   stateRecord
+	snapshot bool
+}
+
+// Estado reconstruido a partir de la instantánea y el diario.
+type restoredState struct {
+	carCounter   int
+	registry     map[string]int
+	tokens       map[int]string
+	faulty       map[string]bool
+	cars         map[int]persistedCar
+	history      map[int][]CrossingRecord
+	queueNorth   []int
+	queueSouth   []int
+	currentDir   string
+	currentCarID int
+	bridge       *bridgeTotals
+	closed       bool
+	closure      *BridgeClosure
+	schedule     []ScheduledClosure
+	scheduleSeq  int
+	policy       string
+	// Hora del último registro aplicado: a partir de ella el servidor estuvo caído.
+	savedAt time.Time
+}
+
+// Restaura el estado guardado e inicia la escritura de la instantánea periódica y del diario.
+func startPersistence() {
+	if stateFile == "" {
+		return
+	}
+
+	if err := restoreState(); err != nil {
+		log.Printf("[Persistencia] No se pudo restaurar el estado: %v. Se inicia una simulación vacía.", err)
+	}
+
+	journal, err := os.OpenFile(journalPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Printf("[Persistencia] No se pudo abrir el diario %s: %v. Persistencia desactivada.", journalPath(), err)
+		return
+	}
+
+	mutex.Lock()
+	persistQueue = newWriteQueue[persistItem]()
+	persistDone = make(chan struct{})
+	go persistLoop(journal, persistQueue)
+	// La instantánea inicial consolida lo restaurado y deja el diario vacío.
+	takeSnapshot()
+	mutex.Unlock()
+
+	ticker := time.NewTicker(snapshotInterval)
+	go func() {
+		defer ticker.Stop()
+		for range ticker.C {
+			mutex.Lock()
+			if persistQueue == nil {
+				mutex.Unlock()
+				return
+			}
+			takeSnapshot()
+			mutex.Unlock()
+		}
+	}()
+}
+
+// Guarda una última instantánea y espera a que se escriba antes de salir.
+func stopPersistence() {
+	mutex.Lock()
+	if persistQueue == nil {
+		mutex.Unlock()
+		return
+	}
+	takeSnapshot()
+	persistQueue.close()
+	persistQueue = nil
+	mutex.Unlock()
+
+	<-persistDone
+}
+
+// Ruta del diario de cambios asociado a la instantánea.
+func journalPath() string {
+	return stateFile + ".journal"
+}
+
+// Registra en el diario el estado actual de los coches indicados y de las colas. Debe llamarse con el mutex bloqueado.
+func journalChange(ids ...int) {
+	if persistQueue == nil {
+		return
+	}
+
+	record := queueRecord()
+	record.Registry = make(map[string]int)
+	for _, id := range ids {
+		car, exists := allCars[id]
+		if !exists {
+			record.Deleted = append(record.Deleted, id)
+			continue
+		}
+		record.Cars = append(record.Cars, toPersistedCar(car))
+		record.Registry[car.UUID] = car.ID
+		if token, ok := vehicleTokens[id]; ok {
+			if record.Tokens == nil {
+				record.Tokens = make(map[int]string)
+			}
+			record.Tokens[id] = token
+		}
+		if faultyClients[car.UUID] {
+			record.Faulty = append(record.Faulty, car.UUID)
+		}
+	}
+	persistQueue.push(persistItem{record: record})
+}
+
+// Registra en el diario un cruce recién terminado. Debe llamarse con el mutex bloqueado.
+func journalCrossing(crossing CrossingRecord) {
+	if persistQueue == nil {
+		return
+	}
+	record := queueRecord()
+	record.Crossings = []CrossingRecord{crossing}
+	persistQueue.push(persistItem{record: record})
+}
+
+// Encola una instantánea completa del estado. Debe llamarse con el mutex bloqueado.
+func takeSnapshot() {
+	persistQueue.push(persistItem{record: snapshotRecord(), snapshot: true})
+}
+
+// Crea un registro con el estado completo de la simulación. Debe llamarse con el mutex bloqueado.
+func snapshotRecord() stateRecord {
+	record := queueRecord()
+	record.Registry = make(map[string]int, len(clientRegistry))
+	for uuid, id := range clientRegistry {
+		record.Registry[uuid] = id
+	}
+	record.Tokens = make(map[int]string, len(vehicleTokens))
+	for id, token := range vehicleTokens {
+		record.Tokens[id] = token
+	}
+	for uuid := range faultyClients {
+		record.Faulty = append(record.Faulty, uuid)
+	}
+	for _, car := range allCars {
+		record.Cars = append(record.Cars, toPersistedCar(car))
+	}
+	for _, history := range crossingHistory {
+		record.Crossings = append(record.Crossings, history...)
+	}
+	return record
+}
+
+// Crea un registro con el contador, el orden de las colas, el coche que cruza y las estadísticas del puente.
+// Debe llamarse con el mutex bloqueado.
+func queueRecord() stateRecord {
+	bridge := bridgeStats.clone()
+	record := stateRecord{
+		SavedAt:      time.Now(),
+		CarCounter:   carCounter,
+		QueueNorth:   carIDs(queueNorth),
+		QueueSouth:   carIDs(queueSouth),
+		CurrentDir:   currentDir,
+		Bridge:       &bridge,
+		BridgeClosed: bridgeClosed,
+		Closure:      currentClosure(),
+		// Copia: el registro se escribe desde otra rutina.
+		Schedule:        append([]ScheduledClosure(nil), closureSchedule...),
+		ScheduleCounter: scheduleCounter,
+		Policy:          activePolicy,
+	}
+	if currentCar != nil {
+		record.CurrentCarID = currentCar.ID
+	}
+	return record
+}
+
+// Devuelve los IDs de los coches de una cola, en orden.
+func carIDs(queue []Car) []int {
+	ids := make([]int, 0, len(queue))
+	for _, car := range queue {
+		ids = append(ids, car.ID)
+	}
+	return ids
+}
+
+// Convierte un coche a su representación en disco.
+func toPersistedCar(car Car) persistedCar {
+	pc := persistedCar{
+		Car:              car,
+		TimeEnteredQueue: car.TimeEnteredQueue,
+		TCP:              car.Conn != nil,
+	}
+	if !car.EvacuatedAt.IsZero() {
+		pc.EvacuatedAt = &car.EvacuatedAt
+	}
+	return pc
+}
+
+// Escribe en disco los cambios e instantáneas en el orden en que se produjeron.
+func persistLoop(journal *os.File, queue *writeQueue[persistItem]) {
+	defer close(persistDone)
+
+	for {
+		items, ok := queue.take()
+		if !ok {
+			break
+		}
+		// Una instantánea ya contiene los cambios anteriores: basta con escribir desde la última.
+		for i := len(items) - 1; i > 0; i-- {
+			if items[i].snapshot {
+				items = items[i:]
+				break
+			}
+		}
+		for _, item := range items {
+			writePersistItem(journal, item)
+		}
+	}
+	journal.Close()
+}
+
+// Escribe una instantánea, vaciando después el diario, o añade un cambio al diario.
+func writePersistItem(journal *os.File, item persistItem) {
+	if item.snapshot {
+		if err := writeSnapshot(item.record); err != nil {
+			log.Printf("[Persistencia] Error guardando la instantánea: %v", err)
+			return
+		}
+		// Lo anterior a la instantánea ya está en ella: el diario vuelve a empezar.
+		if err := journal.Truncate(0); err != nil {
+			log.Printf("[Persistencia] Error vaciando el diario: %v", err)
+		}
+		return
+	}
+
+	data, err := json.Marshal(item.record)
+	if err != nil {
+		log.Printf("[Persistencia] Error codificando un cambio: %v", err)
+		return
+	}
+	if _, err := journal.Write(append(data, '\n')); err != nil {
+		log.Printf("[Persistencia] Error escribiendo en el diario: %v", err)
+	}
+}
+
+// Escribe la instantánea en un archivo temporal y lo renombra para no dejar nunca un archivo a medias.
+// Solo el usuario del servidor puede leerla, porque contiene los tokens de los vehículos.
+func writeSnapshot(record stateRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, stateFile)
+}
+
+// Crea un estado reconstruido vacío, listo para aplicarle la instantánea y el diario.
+func newRestoredState() *restoredState {
+	return &restoredState{
+		registry: make(map[string]int),
+		tokens:   make(map[int]string),
+		faulty:   make(map[string]bool),
+		cars:     make(map[int]persistedCar),
+		history:  make(map[int][]CrossingRecord),
+	}
+}
+
+// Lee la instantánea y el diario, y reconstruye con ellos las variables globales de la simulación.
+func restoreState() error {
+	state := newRestoredState()
+
+	data, err := os.ReadFile(stateFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	found := err == nil
+	if found {
+		var snapshot stateRecord
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return err
+		}
+		state.apply(snapshot)
+	}
+
+	entries, err := readJournal()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		state.apply(entry)
+	}
+
+	// Sin instantánea ni diario no hay nada que restaurar.
+	if !found && len(entries) == 0 {
+		return nil
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	state.install()
+	log.Printf("[Persistencia] Estado restaurado: %d vehículos registrados, %d en cola (norte %d, sur %d).",
+		len(clientRegistry), len(queueNorth)+len(queueSouth), len(queueNorth), len(queueSouth))
+	return nil
+}
+
+// Lee las entradas del diario. Una última línea incompleta, por un corte a mitad de escritura, se descarta.
+func readJournal() ([]stateRecord, error) {
+	file, err := os.Open(journalPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []stateRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry stateRecord
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Printf("[Persistencia] Entrada del diario ilegible descartada: %v", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Aplica un registro de estado sobre lo reconstruido hasta el momento.
+func (s *restoredState) apply(record stateRecord) {
+	s.carCounter = max(s.carCounter, record.CarCounter)
+	if record.SavedAt.After(s.savedAt) {
+		s.savedAt = record.SavedAt
+	}
+	for uuid, id := range record.Registry {
+		s.registry[uuid] = id
+	}
+	for id, token := range record.Tokens {
+		s.tokens[id] = token
+	}
+	for _, uuid := range record.Faulty {
+		s.faulty[uuid] = true
+	}
+	for _, car := range record.Cars {
+		s.cars[car.ID] = car
+	}
+	for _, id := range record.Deleted {
+		delete(s.cars, id)
+	}
+	for _, crossing := range record.Crossings {
+		s.history[crossing.CarID] = append(s.history[crossing.CarID], crossing)
+	}
+	s.queueNorth = record.QueueNorth
+	s.queueSouth = record.QueueSouth
+	s.currentDir = record.CurrentDir
+	s.currentCarID = record.CurrentCarID
+	s.closed = record.BridgeClosed
+	s.closure = record.Closure
+	s.schedule = record.Schedule
+	s.scheduleSeq = max(s.scheduleSeq, record.ScheduleCounter)
+	if record.Policy != "" {
+		s.policy = record.Policy
+	}
+	if record.Bridge != nil {
+		s.bridge = record.Bridge
+	}
+}
+
+// Instala el estado reconstruido: devuelve los coches a sus colas en orden y reanuda los descansos. Debe llamarse con el mutex bloqueado.
+func (s *restoredState) install() {
+	carCounter = s.carCounter
+	clientRegistry = s.registry
+	vehicleTokens = s.tokens
+	faultyClients = s.faulty
+	crossingHistory = s.history
+	currentDir = s.currentDir
+	bridgeClosed = s.closed
+	if s.closure != nil {
+		bridgeClosure = *s.closure
+	} else if s.closed {
+		bridgeClosure = BridgeClosure{Mode: closeDrain, Since: time.Now()}
+	}
+	closureSchedule = s.schedule
+	scheduleCounter = s.scheduleSeq
+	if _, ok := schedulingPolicies[s.policy]; ok && !policyFromFlag {
+		activePolicy = s.policy
+	}
+	if s.bridge != nil {
+		// El puente arranca libre: el tiempo caído no cuenta como ocupación.
+		bridgeStats = *s.bridge
+		bridgeStats.busySince = time.Time{}
+	}
+	now := time.Now()
+	// Tampoco cuenta como tiempo en marcha: el inicio se adelanta lo que duró la caída para no subestimar la ocupación.
+	if s.bridge != nil && !s.savedAt.IsZero() && now.After(s.savedAt) {
+		bridgeStats.StartTime = bridgeStats.StartTime.Add(now.Sub(s.savedAt))
+	}
+
+	// Solo vuelven los coches HTTP: los navegadores conservan su UUID y siguen enviando pings.
+	for id, pc := range s.cars {
+		if pc.TCP {
+			delete(s.cars, id)
+			continue
+		}
+		car := pc.Car
+		car.TimeEnteredQueue = pc.TimeEnteredQueue
+		if pc.EvacuatedAt != nil {
+			car.EvacuatedAt = *pc.EvacuatedAt
+		}
+		car.LastSeen = now
+		car.LeaseExpiresAt = 0
+		allCars[id] = car
+	}
+
+	queued := make(map[int]bool)
+	enqueue := func(id int) {
+		car, exists := allCars[id]
+		if !exists || queued[id] {
+			return
+		}
+		queued[id] = true
+		car.Status = "waiting"
+		allCars[id] = car
+		if car.Direction == "NORTE" {
+			queueNorth = append(queueNorth, car)
+		} else {
+			queueSouth = append(queueSouth, car)
+		}
+	}
+
+	// El coche que cruzaba al caer el servidor vuelve al frente de su cola.
+	enqueue(s.currentCarID)
+	for _, id := range s.queueNorth {
+		enqueue(id)
+	}
+	for _, id := range s.queueSouth {
+		enqueue(id)
+	}
+
+	// Los demás coches se recorren en orden de ID para que la restauración sea determinista.
+	ids := make([]int, 0, len(allCars))
+	for id := range allCars {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		if queued[id] {
+			continue
+		}
+		car := allCars[id]
+		switch {
+		case car.CanRequeueAt > 0:
+			go scheduleRequeue(car, max(time.Until(time.Unix(car.CanRequeueAt, 0)), 0))
+		case car.Status == "waiting" || car.Status == "crossing":
+			enqueue(id)
+		case car.IsLooping:
+			go scheduleRequeue(car, 0)
+		}
+	}
+
+	go processQueue()
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Coche guardado con los datos mínimos para las pruebas de restauración.
+func storedCar(id int, uuid, dir, status string) persistedCar {
+	return persistedCar{Car: Car{Car: api.Car{ID: id, UUID: uuid, Direction: dir, Status: status}}}
+}
+
+func TestRestoredStateApply(t *testing.T) {
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	snapshot := stateRecord{
+		CarCounter: 3,
+		Registry:   map[string]int{"a": 1, "b": 2, "c": 3},
+		Tokens:     map[int]string{1: "t1", 2: "t2"},
+		Cars: []persistedCar{
+			storedCar(1, "a", "NORTE", "waiting"),
+			storedCar(2, "b", "SUR", "waiting"),
+			storedCar(3, "c", "NORTE", "crossing"),
+		},
+		Crossings:    []CrossingRecord{{CarID: 1, Direction: "NORTE", GrantedAt: at}},
+		QueueNorth:   []int{1},
+		QueueSouth:   []int{2},
+		CurrentDir:   "NORTE",
+		CurrentCarID: 3,
+		Policy:       "fifo",
+	}
+
+	tests := []struct {
+		name    string
+		journal []stateRecord
+		check   func(t *testing.T, s *restoredState)
+	}{
+		{
+			name: "solo la instantánea",
+			check: func(t *testing.T, s *restoredState) {
+				if len(s.cars) != 3 || s.carCounter != 3 || s.currentCarID != 3 || s.policy != "fifo" {
+					t.Errorf("cars=%d carCounter=%d currentCarID=%d policy=%q", len(s.cars), s.carCounter, s.currentCarID, s.policy)
+				}
+				if s.tokens[1] != "t1" || s.registry["c"] != 3 {
+					t.Errorf("tokens=%v registry=%v", s.tokens, s.registry)
+				}
+			},
+		},
+		{
+			name: "el diario actualiza un coche y las colas",
+			journal: []stateRecord{{
+				CarCounter: 3,
+				Cars:       []persistedCar{storedCar(3, "c", "SUR", "finished")},
+				QueueNorth: []int{},
+				QueueSouth: []int{2, 3},
+				CurrentDir: "SUR",
+			}},
+			check: func(t *testing.T, s *restoredState) {
+				if car := s.cars[3]; car.Direction != "SUR" || car.Status != "finished" {
+					t.Errorf("coche 3 = %+v", car.Car)
+				}
+				if !reflect.DeepEqual(s.queueSouth, []int{2, 3}) || len(s.queueNorth) != 0 {
+					t.Errorf("colas norte=%v sur=%v", s.queueNorth, s.queueSouth)
+				}
+				if s.currentCarID != 0 || s.currentDir != "SUR" {
+					t.Errorf("currentCarID=%d currentDir=%q", s.currentCarID, s.currentDir)
+				}
+			},
+		},
+		{
+			name: "el diario borra un coche y conserva su token",
+			journal: []stateRecord{
+				{CarCounter: 3, Deleted: []int{1}, QueueSouth: []int{2}},
+			},
+			check: func(t *testing.T, s *restoredState) {
+				if _, exists := s.cars[1]; exists {
+					t.Error("el coche 1 sigue restaurado tras su baja")
+				}
+				if s.tokens[1] != "t1" {
+					t.Error("la baja borró el token del coche 1")
+				}
+			},
+		},
+		{
+			name: "los cruces se acumulan y el contador no retrocede",
+			journal: []stateRecord{
+				{CarCounter: 4, Registry: map[string]int{"d": 4}, Cars: []persistedCar{storedCar(4, "d", "NORTE", "waiting")}},
+				{CarCounter: 2, Crossings: []CrossingRecord{{CarID: 1, Direction: "SUR", GrantedAt: at.Add(time.Minute)}}},
+			},
+			check: func(t *testing.T, s *restoredState) {
+				if len(s.history[1]) != 2 || s.history[1][1].Direction != "SUR" {
+					t.Errorf("historial del coche 1 = %+v", s.history[1])
+				}
+				if s.carCounter != 4 || s.registry["d"] != 4 {
+					t.Errorf("carCounter=%d registry=%v", s.carCounter, s.registry)
+				}
+			},
+		},
+		{
+			name: "una entrada sin política conserva la anterior",
+			journal: []stateRecord{
+				{Policy: "alternate"},
+				{CarCounter: 3},
+			},
+			check: func(t *testing.T, s *restoredState) {
+				if s.policy != "alternate" {
+					t.Errorf("policy=%q, se esperaba alternate", s.policy)
+				}
+			},
+		},
+		{
+			name: "el cierre y el programa siguen a la última entrada",
+			journal: []stateRecord{
+				{BridgeClosed: true, Closure: &BridgeClosure{Mode: closeImmediate, Reason: "obras"}, ScheduleCounter: 5,
+					Schedule: []ScheduledClosure{{ID: 5, Mode: closeDrain}}},
+				{BridgeClosed: false, ScheduleCounter: 2},
+			},
+			check: func(t *testing.T, s *restoredState) {
+				if s.closed || s.closure != nil || len(s.schedule) != 0 {
+					t.Errorf("closed=%v closure=%v schedule=%v", s.closed, s.closure, s.schedule)
+				}
+				if s.scheduleSeq != 5 {
+					t.Errorf("scheduleSeq=%d, se esperaba 5", s.scheduleSeq)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRestoredState()
+			s.apply(snapshot)
+			for _, entry := range tt.journal {
+				s.apply(entry)
+			}
+			tt.check(t, s)
+		})
+	}
+}
+
+func TestReadJournal(t *testing.T) {
+	prev := stateFile
+	stateFile = filepath.Join(t.TempDir(), "estado.json")
+	t.Cleanup(func() { stateFile = prev })
+
+	entries, err := readJournal()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("sin diario: entries=%v err=%v", entries, err)
+	}
+
+	// La última línea quedó cortada a mitad de escritura.
+	data := `{"car_counter":1,"queue_north":[1],"queue_south":[]}
+{"car_counter":2,"queue_north":[1],"queue_south":[2]}
+{"car_counter":3,"queue_no`
+	if err := os.WriteFile(journalPath(), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = readJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].CarCounter != 2 || !reflect.DeepEqual(entries[1].QueueSouth, []int{2}) {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+// Registra un coche HTTP por el manejador real y devuelve su ID y su token.
+func registerHTTPCar(t *testing.T, uuid, dir string) (int, string) {
+	t.Helper()
+	body := strings.NewReader(`{"uuid":"` + uuid + `","direction":"` + dir + `","speed":5}`)
+	rec := httptest.NewRecorder()
+	registerVehicleHandler(rec, httptest.NewRequest(http.MethodPost, "/api/register", body))
+	var resp api.RegisterResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil {
+		t.Fatalf("registro de %s: %d %s", uuid, rec.Code, rec.Body)
+	}
+	return resp.Car.ID, resp.Token
+}
+
+// Sin instantánea final: lo que no esté en la instantánea inicial solo puede volver desde el diario.
+func TestCrashRecoveryReplaysJournal(t *testing.T) {
+	prevFile, prevInterval := stateFile, snapshotInterval
+	stateFile = filepath.Join(t.TempDir(), "estado.json")
+	snapshotInterval = time.Hour
+	t.Cleanup(func() { stateFile, snapshotInterval = prevFile, prevInterval })
+
+	freshSimulation(t)
+	mutex.Lock()
+	bridgeClosed = true
+	mutex.Unlock()
+	startPersistence()
+
+	north, northToken := registerHTTPCar(t, "crash-n", "NORTE")
+	south, _ := registerHTTPCar(t, "crash-s", "SUR")
+	// El registro encola el coche desde otra rutina.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mutex.Lock()
+		queued := len(queueNorth) + len(queueSouth)
+		mutex.Unlock()
+		if queued == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("los coches registrados no llegaron a la cola")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	at := time.Now().Add(-time.Minute).UTC()
+	mutex.Lock()
+	activePolicy = "alternate"
+	journalChange()
+	journalCrossing(CrossingRecord{CarID: north, Direction: "NORTE", GrantedAt: at, DurationSec: 3})
+	// El proceso muere: se escribe lo pendiente pero no hay instantánea de cierre.
+	queue := persistQueue
+	persistQueue = nil
+	mutex.Unlock()
+	queue.close()
+	<-persistDone
+
+	// Una escritura cortada a mitad no impide la restauración.
+	journal, err := os.OpenFile(journalPath(), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.WriteString(`{"car_counter":9,"queue_no`)
+	journal.Close()
+
+	freshSimulation(t)
+	if err := restoreState(); err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if carCounter != 2 || clientRegistry["crash-n"] != north || clientRegistry["crash-s"] != south {
+		t.Errorf("carCounter=%d registro=%v", carCounter, clientRegistry)
+	}
+	if vehicleTokens[north] != northToken {
+		t.Error("el token del coche norte no se restauró")
+	}
+	if len(queueNorth) != 1 || queueNorth[0].ID != north || len(queueSouth) != 1 || queueSouth[0].ID != south {
+		t.Errorf("colas norte=%v sur=%v", carIDs(queueNorth), carIDs(queueSouth))
+	}
+	if car := allCars[south]; car.Status != "waiting" || car.Direction != "SUR" {
+		t.Errorf("coche sur = %+v", car.Car)
+	}
+	if !bridgeClosed || activePolicy != "alternate" {
+		t.Errorf("cerrado=%v política=%q", bridgeClosed, activePolicy)
+	}
+	if h := crossingHistory[north]; len(h) != 1 || !h[0].GrantedAt.Equal(at) {
+		t.Errorf("historial del coche norte = %+v", h)
+	}
+}
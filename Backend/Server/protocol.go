@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Versión más reciente del protocolo TCP que entiende el servidor.
+const protocolVersion = 2
+
+// Cada cuánto se informa a un cliente v2 del avance de su cruce.
+const progressInterval = time.Second
+
+// Capacidad del buffer de mensajes salientes de cada sesión TCP.
+const sessionOutboxSize = 32
+
+// Plazos de la conexión TCP.
+const (
+	// Tiempo máximo para recibir la primera línea de un cliente.
+	handshakeTimeout = 10 * time.Second
+	// Tiempo máximo que una sesión v2 puede permanecer sin cruces ni mensajes.
+	sessionIdleTimeout = 2 * time.Minute
+	// Tiempo máximo para completar la escritura de un mensaje.
+	writeTimeout = 5 * time.Second
+	// Intervalo de los sondeos keep-alive que detectan conexiones caídas.
+	tcpKeepAlivePeriod = 15 * time.Second
+)
+
+// Tipos de mensaje del protocolo v2 (JSON por líneas).
+const (
+	msgHello      = "hello"
+	msgRegister   = "register"
+	msgBye        = "bye"
+	msgExited     = "exited"
+	msgRegistered = "registered"
+	msgQueued     = "queued"
+	msgGranted    = "granted"
+	msgProgress   = "progress"
+	msgFinished   = "finished"
+	msgRevoked    = "revoked"
+	msgError      = "error"
+)
+
+// Mensaje que un cliente envía al servidor en el protocolo v2.
+type clientMessage struct {
+	Type      string `json:"type"`
+	Version   int    `json:"version,omitempty"`
+	UUID      string `json:"uuid,omitempty"`
+	Direction string `json:"direction,omitempty"`
+	Speed     int    `json:"speed,omitempty"`
+	Lease     bool   `json:"lease,omitempty"`
+	Synthetic bool   `json:"synthetic,omitempty"`
+	// Token del vehículo, necesario para volver a registrar un UUID registrado antes por HTTP.
+	Token string `json:"token,omitempty"`
+}
+
+// Mensaje que el servidor envía a un cliente en el protocolo v2.
+type serverMessage struct {
+	Type        string  `json:"type"`
+	Version     int     `json:"version,omitempty"`
+	ID          int     `json:"id,omitempty"`
+	Direction   string  `json:"direction,omitempty"`
+	Position    int     `json:"position,omitempty"`
+	ElapsedSec  float64 `json:"elapsed_sec,omitempty"`
+	DurationSec float64 `json:"duration_sec,omitempty"`
+	WaitSec     float64 `json:"wait_sec,omitempty"`
+	LeaseSec    float64 `json:"lease_sec,omitempty"`
+	Percent     float64 `json:"percent,omitempty"`
+	Code        string  `json:"code,omitempty"`
+	Message     string  `json:"message,omitempty"`
+	Token       string  `json:"token,omitempty"`
+}
+
+// Mensaje pendiente de escritura; si sent no es nil, recibe el resultado de la escritura.
+type outgoingMessage struct {
+	msg  serverMessage
+	sent chan error
+}
+
+// Representa la conexión TCP de un cliente y serializa los mensajes que se le envían.
+type tcpSession struct {
+	conn      net.Conn
+	version   int
+	out       chan outgoingMessage
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// Los siguientes campos están protegidos por el mutex global.
+	uuid   string
+	carID  int
+	active bool
+}
+
+// Crea una sesión para la conexión e inicia la rutina que escribe sus mensajes.
+func newTCPSession(conn net.Conn, version int) *tcpSession {
+	s := &tcpSession{
+		conn:    conn,
+		version: version,
+		out:     make(chan outgoingMessage, sessionOutboxSize),
+		done:    make(chan struct{}),
+	}
+	go s.writeLoop()
+	return s
+}
+
+// Escribe en la conexión, en orden, los mensajes encolados para la sesión.
+func (s *tcpSession) writeLoop() {
+	for {
+		select {
+		case item := <-s.out:
+			err := s.write(item.msg)
+			if item.sent != nil {
+				item.sent <- err
+			}
+			if err != nil {
+				log.Printf("[Sesión %s] Error escribiendo al cliente: %v", s.conn.RemoteAddr(), err)
+				s.close()
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Codifica un mensaje según la versión del protocolo negociada y lo escribe en la conexión.
+func (s *tcpSession) write(msg serverMessage) error {
+	// Un mensaje sin tipo solo marca una posición en el buffer (ver flush).
+	if msg.Type == "" {
+		return nil
+	}
+	if s.version < 2 {
+		// Los clientes v1 solo reciben dos frases: el permiso concedido y el fin del cruce.
+		var line string
+		switch msg.Type {
+		case msgGranted:
+			line = fmt.Sprintf("Auto %d, permiso concedido para cruzar\n", msg.ID)
+		case msgFinished:
+			line = fmt.Sprintf("Auto %d, cruce terminado en %.1f segundos tras esperar %.1f segundos\n", msg.ID, msg.DurationSec, msg.WaitSec)
+		default:
+			return nil
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		_, err := io.WriteString(s.conn, line)
+		return err
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err = s.conn.Write(append(data, '\n'))
+	return err
+}
+
+// Encola un mensaje sin bloquear; si el cliente no consume sus mensajes, se cierra la sesión.
+func (s *tcpSession) send(msg serverMessage) {
+	select {
+	case <-s.done:
+	case s.out <- outgoingMessage{msg: msg}:
+	default:
+		log.Printf("[Sesión %s] Buffer de salida lleno. Cerrando la conexión.", s.conn.RemoteAddr())
+		s.close()
+	}
+}
+
+// Encola un mensaje y espera a que se escriba, devolviendo el error de escritura si lo hubo.
+func (s *tcpSession) sendSync(msg serverMessage) error {
+	item := outgoingMessage{msg: msg, sent: make(chan error, 1)}
+	select {
+	case <-s.done:
+		return net.ErrClosed
+	case s.out <- item:
+	case <-time.After(writeTimeout):
+		return os.ErrDeadlineExceeded
+	}
+
+	select {
+	case err := <-item.sent:
+		return err
+	case <-s.done:
+		return net.ErrClosed
+	}
+}
+
+// Espera a que se escriban los mensajes encolados hasta el momento.
+func (s *tcpSession) flush() {
+	s.sendSync(serverMessage{})
+}
+
+// Envía un mensaje de error con un código legible por máquinas.
+func (s *tcpSession) sendError(code, message string) {
+	s.send(serverMessage{Type: msgError, Code: code, Message: message})
+}
+
+// Cierra la conexión de la sesión una sola vez.
+func (s *tcpSession) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.conn.Close()
+	})
+}
+
+// Indica si la sesión ya fue cerrada.
+func (s *tcpSession) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Indica si el coche tiene una sesión TCP abierta. Debe llamarse con el mutex bloqueado.
+func hasLiveSession(carID int) bool {
+	session, ok := tcpSessions[carID]
+	return ok && !session.closed()
+}
+
+// Envía un mensaje al cliente TCP dueño del coche, si lo tiene. Debe llamarse con el mutex bloqueado.
+func notifyCar(carID int, msg serverMessage) {
+	if session, ok := tcpSessions[carID]; ok {
+		session.send(msg)
+	}
+}
+
+// Envía el permiso de cruce al cliente TCP del coche y espera a que se haya escrito.
+func grantTCPClient(car Car) error {
+	mutex.Lock()
+	session, ok := tcpSessions[car.ID]
+	mutex.Unlock()
+	if !ok {
+		return net.ErrClosed
+	}
+	msg := serverMessage{Type: msgGranted, ID: car.ID, Direction: car.Direction}
+	if car.LeaseMode {
+		msg.LeaseSec = leaseTimeout.Seconds()
+	}
+	return session.sendSync(msg)
+}
+
+// Informa a los clientes en espera de su posición actual en la cola. Debe llamarse con el mutex bloqueado.
+func notifyQueuePositions() {
+	for _, queue := range [][]Car{queueNorth, queueSouth} {
+		for i, car := range queue {
+			notifyCar(car.ID, serverMessage{Type: msgQueued, ID: car.ID, Direction: car.Direction, Position: i + 1})
+		}
+	}
+}
+
+// Atiende una sesión v2: negocia la versión y procesa los mensajes del cliente hasta que se desconecta.
+func handleSessionV2(conn net.Conn, reader *bufio.Reader, firstLine string) {
+	session := newTCPSession(conn, protocolVersion)
+	defer endSession(session)
+
+	var hello clientMessage
+	if err := json.Unmarshal([]byte(firstLine), &hello); err != nil || hello.Type != msgHello {
+		session.sendError("bad_hello", "Se esperaba un mensaje hello como primera línea")
+		return
+	}
+	if hello.Version < 2 || hello.Version > protocolVersion {
+		session.sendError("unsupported_version", fmt.Sprintf("Versión %d no soportada; máxima: %d", hello.Version, protocolVersion))
+		return
+	}
+	session.send(serverMessage{Type: msgHello, Version: protocolVersion})
+	log.Printf("Cliente %s conectado con el protocolo v%d", conn.RemoteAddr(), hello.Version)
+	conn.SetReadDeadline(time.Now().Add(sessionIdleTimeout))
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				log.Printf("Cliente %s inactivo durante %v. Cerrando la sesión.", conn.RemoteAddr(), sessionIdleTimeout)
+				session.sendError("idle_timeout", "Sesión cerrada por inactividad")
+			}
+			return
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var msg clientMessage
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			session.sendError("bad_json", "Mensaje JSON inválido")
+			continue
+		}
+
+		switch msg.Type {
+		case msgRegister:
+			registerSessionCar(session, msg)
+		case msgExited:
+			mutex.Lock()
+			carID := session.carID
+			mutex.Unlock()
+			if !reportExit(carID) {
+				session.sendError("no_lease", "El vehículo no tiene el puente arrendado")
+			}
+		case msgBye:
+			return
+		default:
+			session.sendError("unknown_type", fmt.Sprintf("Tipo de mensaje desconocido: %q", msg.Type))
+		}
+	}
+}
+
+// Registra el coche de una sesión v2 y solicita un nuevo cruce para él.
+func registerSessionCar(session *tcpSession, msg clientMessage) {
+	direction := strings.ToUpper(msg.Direction)
+	if msg.UUID == "" {
+		session.sendError("missing_uuid", "El campo uuid es obligatorio")
+		return
+	}
+	if direction != "NORTE" && direction != "SUR" {
+		session.sendError("invalid_direction", "La dirección debe ser NORTE o SUR")
+		return
+	}
+
+	mutex.Lock()
+	if session.active {
+		mutex.Unlock()
+		session.sendError("crossing_in_progress", "Ya hay un cruce en curso en esta conexión")
+		return
+	}
+	if session.uuid != "" && session.uuid != msg.UUID {
+		mutex.Unlock()
+		session.sendError("uuid_mismatch", "La conexión ya pertenece a otro vehículo")
+		return
+	}
+
+	assignedID, exists := clientRegistry[msg.UUID]
+	// La propia sesión puede volver a registrar su coche tras cada cruce; cualquier otra necesita su token.
+	ownSession := exists && tcpSessions[assignedID] == session
+	if exists && !ownSession && !canClaimVehicle(assignedID, msg.Token) {
+		mutex.Unlock()
+		session.sendError("unauthorized", "El UUID pertenece a un vehículo protegido por token o en uso por otra conexión. Envíe su token para registrarlo")
+		return
+	}
+	if !exists {
+		carCounter++
+		assignedID = carCounter
+		clientRegistry[msg.UUID] = assignedID
+		log.Printf("Nuevo vehículo detectado (UUID: %s). Asignado ID numérico: %d", msg.UUID, assignedID)
+	}
+	token, err := vehicleToken(assignedID)
+	if err != nil {
+		mutex.Unlock()
+		log.Printf("Error generando el token del auto %d: %v", assignedID, err)
+		session.sendError("internal_error", "No se pudo generar el token del vehículo")
+		return
+	}
+
+	car := Car{
+		Car: api.Car{
+			ID:        assignedID,
+			UUID:      msg.UUID,
+			Direction: direction,
+			Speed:     msg.Speed,
+			Status:    "waiting",
+			LeaseMode: msg.Lease,
+			Synthetic: msg.Synthetic,
+			Faulty:    faultyClients[msg.UUID],
+			Stats: CarStats{
+				TimeRegistered: time.Now(),
+			},
+		},
+		Conn:             session.conn,
+		TimeEnteredQueue: time.Now(),
+		LastSeen:         time.Now(),
+	}
+
+	session.uuid = msg.UUID
+	session.carID = assignedID
+	session.active = true
+	tcpSessions[assignedID] = session
+	allCars[car.ID] = car
+	journalChange(car.ID)
+	registered := carEvent(evRegistered, car)
+	registered.Transport = "tcp"
+	recordEvent(registered)
+	// El token permite volver a registrar el UUID desde otra conexión y usar las rutas del vehículo en la API.
+	session.send(serverMessage{Type: msgRegistered, ID: car.ID, Token: token})
+	mutex.Unlock()
+
+	// Mientras espera o cruza, el cliente puede permanecer en silencio sin que expire la sesión.
+	session.conn.SetReadDeadline(time.Time{})
+
+	log.Printf("[Auto %d] solicita cruzar desde %s (protocolo v2)", car.ID, car.Direction)
+	requestCross(car)
+}
+
+// Libera los recursos de una sesión al terminar y la desvincula de su coche.
+func endSession(session *tcpSession) {
+	mutex.Lock()
+	if current, ok := tcpSessions[session.carID]; ok && current == session {
+		dropSessionCar(session)
+		delete(tcpSessions, session.carID)
+	}
+	mutex.Unlock()
+
+	// Entrega los últimos mensajes (por ejemplo, un error) antes de cerrar la conexión.
+	session.flush()
+	session.close()
+}
+
+// Retira de las colas el coche de una sesión cuyo cliente se desconectó mientras esperaba.
+// Un coche que ya está cruzando termina su cruce. Debe llamarse con el mutex bloqueado.
+func dropSessionCar(session *tcpSession) {
+	if !session.active {
+		return
+	}
+	id := session.carID
+	if currentCar != nil && currentCar.ID == id {
+		return
+	}
+	if _, exists := allCars[id]; !exists {
+		return
+	}
+
+	log.Printf("[Auto %d] El cliente TCP se desconectó mientras esperaba. Eliminando de la cola.", id)
+	removed := carEvent(evRemoved, allCars[id])
+	removed.Reason = "disconnected"
+	recordEvent(removed)
+	delete(allCars, id)
+	queueNorth = removeCarFromSlice(queueNorth, id)
+	queueSouth = removeCarFromSlice(queueSouth, id)
+	session.active = false
+	noteQueueChange(time.Now())
+	notifyQueuePositions()
+	journalChange(id)
+}
+
+// Marca el final del cruce de una sesión TCP. Debe llamarse con el mutex bloqueado.
+func finishSessionCrossing(carID int) {
+	session, ok := tcpSessions[carID]
+	if !ok {
+		return
+	}
+	session.active = false
+
+	// Las sesiones v1 solo sirven para un cruce y se cierran tras entregar la frase de fin; las v2 vuelven
+	// a contar su inactividad.
+	if session.version < 2 {
+		delete(tcpSessions, carID)
+		go func() {
+			session.flush()
+			session.close()
+		}()
+		return
+	}
+	session.conn.SetReadDeadline(time.Now().Add(sessionIdleTimeout))
+}
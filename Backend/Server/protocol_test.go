@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// Extremo cliente de una conexión TCP simulada, atendida por handleClient en el otro extremo.
+type testClient struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+// Abre una conexión en memoria con el servidor. Al terminar la prueba la cierra y espera a que el servidor
+// suelte la sesión, para que su limpieza no alcance a la prueba siguiente.
+func dialTestClient(t *testing.T) *testClient {
+	t.Helper()
+	client, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		handleClient(server)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		client.Close()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Error("el servidor no cerró la sesión tras la desconexión")
+		}
+	})
+	client.SetDeadline(time.Now().Add(10 * time.Second))
+	return &testClient{t: t, conn: client, scanner: bufio.NewScanner(client)}
+}
+
+// Envía un mensaje v2.
+func (c *testClient) send(msg clientMessage) {
+	c.t.Helper()
+	data, _ := json.Marshal(msg)
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		c.t.Fatalf("enviando %s: %v", msg.Type, err)
+	}
+}
+
+// Lee mensajes hasta recibir uno del tipo indicado; los de cola y avance se saltan.
+func (c *testClient) expect(msgType string) serverMessage {
+	c.t.Helper()
+	for c.scanner.Scan() {
+		var msg serverMessage
+		if err := json.Unmarshal(c.scanner.Bytes(), &msg); err != nil {
+			c.t.Fatalf("respuesta no es JSON: %q", c.scanner.Text())
+		}
+		if msg.Type == msgQueued || msg.Type == msgProgress {
+			continue
+		}
+		if msg.Type != msgType {
+			c.t.Fatalf("se esperaba %s y llegó %+v", msgType, msg)
+		}
+		return msg
+	}
+	c.t.Fatalf("la conexión se cerró esperando %s: %v", msgType, c.scanner.Err())
+	return serverMessage{}
+}
+
+func TestSessionV2Handshake(t *testing.T) {
+	freshSimulation(t)
+
+	c := dialTestClient(t)
+	c.send(clientMessage{Type: msgHello, Version: 2})
+	if hello := c.expect(msgHello); hello.Version != protocolVersion {
+		t.Errorf("versión negociada %d, se esperaba %d", hello.Version, protocolVersion)
+	}
+
+	c.send(clientMessage{Type: "honk"})
+	if e := c.expect(msgError); e.Code != "unknown_type" {
+		t.Errorf("código %q, se esperaba unknown_type", e.Code)
+	}
+	c.send(clientMessage{Type: msgRegister, UUID: "u1", Direction: "ESTE"})
+	if e := c.expect(msgError); e.Code != "invalid_direction" {
+		t.Errorf("código %q, se esperaba invalid_direction", e.Code)
+	}
+
+	// La sesión sigue abierta tras los errores y se cierra con bye.
+	c.send(clientMessage{Type: msgBye})
+	if c.scanner.Scan() {
+		t.Errorf("llegó %q tras bye; se esperaba el cierre", c.scanner.Text())
+	}
+}
+
+func TestSessionV2RejectsUnsupportedVersion(t *testing.T) {
+	freshSimulation(t)
+
+	for _, version := range []int{1, protocolVersion + 1} {
+		c := dialTestClient(t)
+		c.send(clientMessage{Type: msgHello, Version: version})
+		if e := c.expect(msgError); e.Code != "unsupported_version" {
+			t.Errorf("versión %d: código %q, se esperaba unsupported_version", version, e.Code)
+		}
+	}
+}
+
+// Con el puente cerrado el coche se queda en cola, así que la sesión se puede examinar sin esperar a un cruce.
+func TestSessionV2RegisterQueuesCar(t *testing.T) {
+	freshSimulation(t)
+	mutex.Lock()
+	bridgeClosed = true
+	mutex.Unlock()
+
+	c := dialTestClient(t)
+	c.send(clientMessage{Type: msgHello, Version: 2})
+	c.expect(msgHello)
+	c.send(clientMessage{Type: msgRegister, UUID: "u1", Direction: "norte", Speed: 5})
+	registered := c.expect(msgRegistered)
+	if registered.ID == 0 || registered.Token == "" {
+		t.Fatalf("registered = %+v, se esperaba un ID y un token", registered)
+	}
+
+	c.send(clientMessage{Type: msgRegister, UUID: "u1", Direction: "SUR"})
+	if e := c.expect(msgError); e.Code != "crossing_in_progress" {
+		t.Errorf("segundo registro: código %q, se esperaba crossing_in_progress", e.Code)
+	}
+
+	mutex.Lock()
+	queued := len(queueNorth) == 1 && queueNorth[0].ID == registered.ID
+	token := vehicleTokens[registered.ID]
+	mutex.Unlock()
+	if !queued {
+		t.Error("el coche no está en la cola norte")
+	}
+	if token != registered.Token {
+		t.Error("el token enviado no es el que guarda el servidor")
+	}
+
+	// Al desconectarse mientras espera, el coche sale de la cola y del registro.
+	c.conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mutex.Lock()
+		_, exists := allCars[registered.ID]
+		waiting := len(queueNorth)
+		mutex.Unlock()
+		if !exists && waiting == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("el coche sigue registrado tras la desconexión")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Un cliente v1 recibe el fin del cruce como una frase y luego el servidor cierra su conexión.
+func TestFinishedLineV1(t *testing.T) {
+	freshSimulation(t)
+	client, server := net.Pipe()
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	session := newTCPSession(server, 1)
+	mutex.Lock()
+	session.carID, session.active = 7, true
+	tcpSessions[7] = session
+	// Los mensajes que v1 no conoce no llegan al cliente.
+	notifyCar(7, serverMessage{Type: msgQueued, ID: 7, Position: 1})
+	notifyCar(7, serverMessage{Type: msgFinished, ID: 7, DurationSec: 4, WaitSec: 1.5})
+	finishSessionCrossing(7)
+	_, stillOpen := tcpSessions[7]
+	mutex.Unlock()
+	if stillOpen {
+		t.Error("la sesión v1 sigue registrada tras el cruce")
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(client)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	want := "Auto 7, cruce terminado en 4.0 segundos tras esperar 1.5 segundos"
+	if len(lines) != 1 || lines[0] != want {
+		t.Errorf("líneas recibidas %q, se esperaba solo %q", lines, want)
+	}
+}
+
+// Un cliente v2 recibe finished con los tiempos del servidor y la sesión sigue abierta para otro cruce.
+func TestFinishedMessageV2(t *testing.T) {
+	freshSimulation(t)
+	client, server := net.Pipe()
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	session := newTCPSession(server, 2)
+	defer session.close()
+	mutex.Lock()
+	session.carID, session.active = 3, true
+	tcpSessions[3] = session
+	notifyCar(3, serverMessage{Type: msgFinished, ID: 3, Direction: "SUR", DurationSec: 6.5, WaitSec: 2})
+	finishSessionCrossing(3)
+	mutex.Unlock()
+
+	c := &testClient{t: t, conn: client, scanner: bufio.NewScanner(client)}
+	finished := c.expect(msgFinished)
+	if finished.ID != 3 || finished.Direction != "SUR" || finished.DurationSec != 6.5 || finished.WaitSec != 2 {
+		t.Errorf("finished = %+v", finished)
+	}
+
+	mutex.Lock()
+	current, open := tcpSessions[3]
+	active := session.active
+	mutex.Unlock()
+	if !open || current != session || active {
+		t.Errorf("sesión registrada=%v activa=%v; se esperaba registrada y sin cruce", open, active)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Estadísticas de un vehículo reconstruidas a partir del registro de eventos.
+type replayCarStats struct {
+	UUID      string
+	Crossings int
+	WaitSec   float64
+	CrossSec  float64
+	MaxWait   float64
+	Abandoned int
+	Revoked   int
+}
+
+// Pausa máxima entre dos eventos de la reproducción en vivo, ya aplicada la velocidad. Evita quedarse
+// esperando durante los ratos en que la sesión original no tuvo actividad.
+const maxReplayGap = 5 * time.Second
+
+// Adelantamientos sufridos por cada coche que cruza durante la reproducción, para guardarlos con su cruce.
+// Protegido por el mutex global.
+var replayOvertaken = make(map[int]int)
+
+// Subcomando "replay": reconstruye una sesión pasada a partir de su registro de eventos.
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	live := fs.Bool("live", false, "reproduce la sesión en la API HTTP (:8080) para verla en el frontend")
+	speed := fs.Float64("speed", 1, "factor de velocidad de la reproducción en vivo (2 = el doble de rápido)")
+	quiet := fs.Bool("quiet", false, "muestra solo el resumen final, sin la línea de tiempo")
+	fs.DurationVar(&sampleInterval, "sample-interval", time.Second, "cada cuánto se muestrean las colas durante la reproducción en vivo")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Uso: go run . replay [opciones] <archivo de eventos>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *speed <= 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if sampleInterval <= 0 {
+		log.Fatalf("-sample-interval debe ser mayor que cero: %v", sampleInterval)
+	}
+
+	events, err := loadEvents(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error leyendo el registro de eventos: %v", err)
+	}
+	if len(events) == 0 {
+		log.Fatalf("El registro %s no contiene eventos", fs.Arg(0))
+	}
+
+	if *live {
+		replayLive(events, *speed, *quiet)
+	} else if !*quiet {
+		for _, ev := range events {
+			fmt.Println(formatEvent(ev, events[0].Time))
+		}
+	}
+	printReplaySummary(events)
+}
+
+// Lee todos los eventos de un archivo, ordenados por número de secuencia.
+func loadEvents(path string) ([]bridgeEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []bridgeEvent
+	err = readEvents(file, func(ev bridgeEvent) error {
+		events = append(events, ev)
+		return nil
+	})
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events, err
+}
+
+// Reproduce los eventos sobre el estado global respetando sus intervalos, mientras la API HTTP los sirve.
+func replayLive(events []bridgeEvent, speed float64, quiet bool) {
+	mutex.Lock()
+	bridgeStats = newBridgeTotals(time.Now())
+	mutex.Unlock()
+	go startHTTPServer(true)
+	go sampleTimeSeries()
+	log.Printf("Reproduciendo %d eventos a velocidad x%g. Abra el frontend en modo repetición (/simulacion?replay=1).", len(events), speed)
+
+	for i, ev := range events {
+		// El tiempo que el servidor estuvo apagado no se reproduce.
+		if i > 0 && ev.Type != evServerStarted {
+			gap := time.Duration(float64(ev.Time.Sub(events[i-1].Time)) / speed)
+			time.Sleep(min(gap, maxReplayGap))
+		}
+
+		mutex.Lock()
+		applyReplayEvent(ev, speed)
+		mutex.Unlock()
+
+		if !quiet {
+			fmt.Println(formatEvent(ev, events[0].Time))
+		}
+	}
+
+	log.Println("Reproducción terminada. La API sigue mostrando el estado final; presione Ctrl+C para salir.")
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+}
+
+// Aplica un evento sobre las variables globales de la simulación, incluidas las estadísticas del puente y el
+// historial de cruces. Las esperas y duraciones salen del evento; los periodos de ocupación y de espera sin
+// paso se miden en el reloj de la reproducción. Debe llamarse con el mutex bloqueado.
+func applyReplayEvent(ev bridgeEvent, speed float64) {
+	now := time.Now()
+	defer noteQueueChange(now)
+
+	switch ev.Type {
+	case evBridgeClosed:
+		bridgeClosed = true
+		bridgeClosure = BridgeClosure{Mode: ev.Reason, Since: time.Now()}
+	case evBridgeOpened:
+		bridgeClosed = false
+		bridgeClosure = BridgeClosure{}
+	case evSimulationReset:
+		allCars = make(map[int]Car)
+		queueNorth, queueSouth = nil, nil
+		clientRegistry = make(map[string]int)
+		crossingHistory = make(map[int][]CrossingRecord)
+		replayOvertaken = make(map[int]int)
+		bridgeStats = newBridgeTotals(now)
+		timeSeries.reset()
+		bridgeClosed = false
+		bridgeBusy = false
+		currentCar = nil
+		currentDir = ""
+	}
+	if ev.CarID == 0 {
+		return
+	}
+
+	car, exists := allCars[ev.CarID]
+	if !exists {
+		car = Car{Car: api.Car{ID: ev.CarID, UUID: ev.UUID, Stats: CarStats{TimeRegistered: time.Now()}}}
+	}
+	if ev.Direction != "" {
+		car.Direction = ev.Direction
+	}
+	if ev.Speed != 0 {
+		car.Speed = ev.Speed
+	}
+	clientRegistry[car.UUID] = car.ID
+	carCounter = max(carCounter, car.ID)
+
+	isCurrent := currentCar != nil && currentCar.ID == car.ID
+	releaseBridge := func() {
+		if isCurrent {
+			noteBridgeRelease(now)
+			bridgeBusy = false
+			currentCar = nil
+		}
+	}
+
+	switch ev.Type {
+	case evRegistered:
+		car.Status = "waiting"
+		car.IsLooping = ev.Transport == "http"
+	case evQueued:
+		car.Status = "waiting"
+		car.CanRequeueAt = 0
+		car.TimeEnteredQueue = ev.Time
+		car.QueueLengthAtArrival = max(ev.Position-1, 0)
+		queueNorth = removeCarFromSlice(queueNorth, car.ID)
+		queueSouth = removeCarFromSlice(queueSouth, car.ID)
+		if car.Direction == "NORTE" {
+			queueNorth = append(queueNorth, car)
+		} else {
+			queueSouth = append(queueSouth, car)
+		}
+	case evGranted:
+		car.Status = "crossing"
+		car.CanRequeueAt = 0
+		wait := secondsToDuration(ev.WaitSec)
+		noteVehicleGrant(&car.Stats, car.Direction, wait)
+		// Igual que en la simulación, tras una evacuación el puente solo suma la espera desde ella.
+		bridgeWait := wait
+		if car.EvacuatedAt.IsZero() {
+			noteOvertakes(car)
+		} else {
+			bridgeWait = ev.Time.Sub(car.EvacuatedAt)
+			car.EvacuatedAt = time.Time{}
+		}
+		replayOvertaken[car.ID] = car.OvertakenInQueue
+		car.OvertakenInQueue = 0
+		queueNorth = removeCarFromSlice(queueNorth, car.ID)
+		queueSouth = removeCarFromSlice(queueSouth, car.ID)
+		noteBridgeGrant(car.Direction, bridgeWait, now)
+		bridgeBusy = true
+		currentDir = car.Direction
+		crossing := car
+		currentCar = &crossing
+	case evFinished:
+		car.Status = "finished"
+		crossed := secondsToDuration(ev.DurationSec)
+		noteVehicleCrossing(&car.Stats, car.Direction, crossed)
+		if isCurrent {
+			noteBridgeExit(car.Direction, crossed, now)
+		}
+		releaseBridge()
+		recordCrossing(CrossingRecord{
+			CarID:                car.ID,
+			Direction:            car.Direction,
+			QueuedAt:             ev.Time.Add(-crossed - secondsToDuration(ev.WaitSec)),
+			GrantedAt:            ev.Time.Add(-crossed),
+			ExitedAt:             ev.Time,
+			WaitSec:              ev.WaitSec,
+			DurationSec:          ev.DurationSec,
+			QueueLengthAtArrival: car.QueueLengthAtArrival,
+			TimesOvertaken:       replayOvertaken[car.ID],
+		})
+		delete(replayOvertaken, car.ID)
+	case evResting:
+		car.CanRequeueAt = time.Now().Add(time.Duration(float64(secondsToDuration(ev.RestSec)) / speed)).Unix()
+		noteVehicleRest(&car.Stats, secondsToDuration(ev.RestSec))
+	case evStopped:
+		car.IsLooping = false
+	case evCancelled:
+		car.Status = "cancelled"
+		car.IsLooping = false
+		car.CanRequeueAt = 0
+		queueNorth = removeCarFromSlice(queueNorth, car.ID)
+		queueSouth = removeCarFromSlice(queueSouth, car.ID)
+	case evAbandoned:
+		car.Status = "abandoned"
+		car.Stats.AbandonedCrossings++
+		bridgeStats.AbandonedCrossings++
+		releaseBridge()
+	case evEvacuated:
+		car.Status = "waiting"
+		undoVehicleGrant(&car.Stats, car.Direction, secondsToDuration(ev.WaitSec))
+		car.EvacuatedAt = ev.Time
+		car.OvertakenInQueue = replayOvertaken[car.ID]
+		delete(replayOvertaken, car.ID)
+		bridgeStats.EvacuatedCrossings++
+		releaseBridge()
+		queueNorth = removeCarFromSlice(queueNorth, car.ID)
+		queueSouth = removeCarFromSlice(queueSouth, car.ID)
+		if car.Direction == "NORTE" {
+			queueNorth = append([]Car{car}, queueNorth...)
+		} else {
+			queueSouth = append([]Car{car}, queueSouth...)
+		}
+	case evRevoked:
+		car.Status = "faulty"
+		car.Faulty = true
+		car.Stats.RevokedLeases++
+		bridgeStats.RevokedLeases++
+		releaseBridge()
+		delete(replayOvertaken, car.ID)
+	case evRemoved:
+		delete(allCars, car.ID)
+		delete(replayOvertaken, car.ID)
+		queueNorth = removeCarFromSlice(queueNorth, car.ID)
+		queueSouth = removeCarFromSlice(queueSouth, car.ID)
+		releaseBridge()
+		return
+	}
+	allCars[car.ID] = car
+}
+
+// Convierte segundos con decimales a time.Duration.
+func secondsToDuration(sec float64) time.Duration {
+	return time.Duration(sec * float64(time.Second))
+}
+
+// Describe un evento en una línea de la línea de tiempo, con su desfase respecto al inicio.
+func formatEvent(ev bridgeEvent, start time.Time) string {
+	offset := ev.Time.Sub(start).Round(100 * time.Millisecond)
+	prefix := fmt.Sprintf("+%-10v #%-6d %-14s", offset, ev.Seq, ev.Type)
+
+	var detail string
+	switch ev.Type {
+	case evServerStarted:
+		detail = "Servidor iniciado"
+	case evRegistered:
+		detail = fmt.Sprintf("Auto %d (%s) registrado vía %s, %s, velocidad %d", ev.CarID, ev.UUID, ev.Transport, ev.Direction, ev.Speed)
+	case evQueued:
+		detail = fmt.Sprintf("Auto %d en cola %s, posición %d", ev.CarID, ev.Direction, ev.Position)
+	case evGranted:
+		detail = fmt.Sprintf("Auto %d entra al puente hacia el %s tras esperar %.1fs", ev.CarID, ev.Direction, ev.WaitSec)
+	case evFinished:
+		detail = fmt.Sprintf("Auto %d sale del puente tras %.1fs", ev.CarID, ev.DurationSec)
+	case evResting:
+		detail = fmt.Sprintf("Auto %d descansa %.0fs", ev.CarID, ev.RestSec)
+	case evStopped:
+		detail = fmt.Sprintf("Auto %d no volverá a la cola", ev.CarID)
+	case evCancelled:
+		detail = fmt.Sprintf("Auto %d retirado de la cola por un operador", ev.CarID)
+	case evBridgeClosed:
+		detail = fmt.Sprintf("Puente cerrado (%s)", ev.Reason)
+	case evBridgeOpened:
+		detail = "Puente abierto"
+		if ev.Reason == "scheduled" {
+			detail += " al terminar el cierre previsto"
+		}
+	case evEvacuated:
+		detail = fmt.Sprintf("Auto %d evacuado del puente, vuelve al frente de la cola %s", ev.CarID, ev.Direction)
+	case evSimulationReset:
+		detail = "Simulación reiniciada por la administración"
+	case evRemoved, evAbandoned, evRevoked:
+		detail = fmt.Sprintf("Auto %d (%s)", ev.CarID, ev.Reason)
+	default:
+		detail = fmt.Sprintf("Auto %d", ev.CarID)
+	}
+	return prefix + " " + detail
+}
+
+// Calcula e imprime las estadísticas finales de la sesión a partir de sus eventos.
+func printReplaySummary(events []bridgeEvent) {
+	cars := make(map[int]*replayCarStats)
+	crossingsByDir := make(map[string]int)
+	waitByDir := make(map[string]float64)
+	var abandoned, revoked, evacuations, restarts int
+
+	for _, ev := range events {
+		if ev.Type == evServerStarted {
+			restarts++
+			continue
+		}
+		// Los eventos del puente, como su cierre, no pertenecen a ningún vehículo.
+		if ev.CarID == 0 {
+			continue
+		}
+		stats, ok := cars[ev.CarID]
+		if !ok {
+			stats = &replayCarStats{UUID: ev.UUID}
+			cars[ev.CarID] = stats
+		}
+
+		switch ev.Type {
+		case evFinished:
+			stats.Crossings++
+			stats.WaitSec += ev.WaitSec
+			stats.CrossSec += ev.DurationSec
+			stats.MaxWait = max(stats.MaxWait, ev.WaitSec)
+			crossingsByDir[ev.Direction]++
+			waitByDir[ev.Direction] += ev.WaitSec
+		case evAbandoned:
+			stats.Abandoned++
+			abandoned++
+		case evRevoked:
+			stats.Revoked++
+			revoked++
+		case evEvacuated:
+			evacuations++
+		}
+	}
+
+	first, last := events[0], events[len(events)-1]
+	fmt.Println("\n=== RESUMEN DE LA SESIÓN ===")
+	fmt.Printf("Eventos: %d (secuencia %d a %d)\n", len(events), first.Seq, last.Seq)
+	fmt.Printf("Desde %s hasta %s (%v)\n", first.Time.Format(time.DateTime), last.Time.Format(time.DateTime), last.Time.Sub(first.Time).Round(time.Second))
+	fmt.Printf("Inicios del servidor: %d\n", restarts)
+	fmt.Printf("Cruces: %d (NORTE %d, SUR %d)\n", crossingsByDir["NORTE"]+crossingsByDir["SUR"], crossingsByDir["NORTE"], crossingsByDir["SUR"])
+	for _, dir := range []string{"NORTE", "SUR"} {
+		if crossingsByDir[dir] > 0 {
+			fmt.Printf("Espera promedio %s: %.1fs\n", dir, waitByDir[dir]/float64(crossingsByDir[dir]))
+		}
+	}
+	fmt.Printf("Cruces abandonados: %d, arrendamientos revocados: %d, evacuaciones: %d\n", abandoned, revoked, evacuations)
+
+	ids := make([]int, 0, len(cars))
+	for id := range cars {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	fmt.Println("\nVehículos:")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUUID\tCruces\tEspera prom.\tEspera máx.\tCruce prom.\tAbandonados\tRevocados")
+	for _, id := range ids {
+		s := cars[id]
+		avgWait, avgCross := 0.0, 0.0
+		if s.Crossings > 0 {
+			avgWait = s.WaitSec / float64(s.Crossings)
+			avgCross = s.CrossSec / float64(s.Crossings)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%.1fs\t%.1fs\t%.1fs\t%d\t%d\n", id, s.UUID, s.Crossings, avgWait, s.MaxWait, avgCross, s.Abandoned, s.Revoked)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Política de paso con la que arranca el servidor si no se indica otra.
+const defaultPolicy = "keep_direction"
+
+// Decide de qué cola sale el próximo coche a partir de ambas colas y de la dirección que tiene paso.
+// Devuelve "NORTE", "SUR" o "" si no hay nadie esperando. Se llama con el mutex bloqueado.
+type schedulingPolicy func(north, south []Car, current string) string
+
+// Políticas de paso disponibles, por nombre.
+var schedulingPolicies = map[string]schedulingPolicy{
+	"keep_direction": keepDirectionPolicy,
+	"alternate":      alternatePolicy,
+	"fifo":           fifoPolicy,
+}
+
+// Política de paso activa. Protegida por el mutex global.
+var activePolicy = defaultPolicy
+
+// Indica si la política se eligió con -policy; en ese caso manda sobre la guardada en disco.
+var policyFromFlag bool
+
+// Nombres de las políticas disponibles, en orden alfabético.
+func policyNames() []string {
+	names := make([]string, 0, len(schedulingPolicies))
+	for name := range schedulingPolicies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Mantiene el paso en la dirección actual mientras tenga coches; solo cambia cuando su cola se vacía.
+func keepDirectionPolicy(north, south []Car, current string) string {
+	switch {
+	case current == "NORTE" && len(north) > 0:
+		return "NORTE"
+	case current == "SUR" && len(south) > 0:
+		return "SUR"
+	case len(north) > 0:
+		return "NORTE"
+	case len(south) > 0:
+		return "SUR"
+	}
+	return ""
+}
+
+// Cambia de dirección tras cada cruce si en la otra cola hay alguien esperando.
+func alternatePolicy(north, south []Car, current string) string {
+	switch {
+	case current == "NORTE" && len(south) > 0:
+		return "SUR"
+	case current == "SUR" && len(north) > 0:
+		return "NORTE"
+	}
+	return keepDirectionPolicy(north, south, current)
+}
+
+// Da paso al primero que llegó a la cola, sin importar su dirección.
+func fifoPolicy(north, south []Car, current string) string {
+	switch {
+	case len(north) == 0 && len(south) == 0:
+		return ""
+	case len(south) == 0:
+		return "NORTE"
+	case len(north) == 0:
+		return "SUR"
+	}
+	if queuedSince(south[0]).Before(queuedSince(north[0])) {
+		return "SUR"
+	}
+	return "NORTE"
+}
+
+// Momento en que el coche entró en la cola. La copia encolada puede ser anterior a su último descanso,
+// así que se prefiere el dato del registro. Debe llamarse con el mutex bloqueado.
+func queuedSince(car Car) time.Time {
+	if stored, ok := allCars[car.ID]; ok {
+		return stored.TimeEnteredQueue
+	}
+	return car.TimeEnteredQueue
+}
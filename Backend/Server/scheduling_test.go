@@ -0,0 +1,192 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+func TestSchedulingPolicies(t *testing.T) {
+	prevCars := allCars
+	allCars = make(map[int]Car)
+	t.Cleanup(func() { allCars = prevCars })
+
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	// Coche en cola desde base más los segundos indicados.
+	queued := func(id int, dir string, sec int) Car {
+		return Car{Car: api.Car{ID: id, Direction: dir}, TimeEnteredQueue: base.Add(time.Duration(sec) * time.Second)}
+	}
+	north := []Car{queued(1, "NORTE", 10), queued(2, "NORTE", 20)}
+	south := []Car{queued(3, "SUR", 5)}
+
+	tests := []struct {
+		name    string
+		policy  string
+		north   []Car
+		south   []Car
+		current string
+		want    string
+	}{
+		{name: "colas vacías", policy: "keep_direction", current: "NORTE", want: ""},
+		{name: "sigue en su dirección", policy: "keep_direction", north: north, south: south, current: "NORTE", want: "NORTE"},
+		{name: "cambia al vaciarse su cola", policy: "keep_direction", south: south, current: "NORTE", want: "SUR"},
+		{name: "sin dirección previa empieza por el norte", policy: "keep_direction", north: north, south: south, want: "NORTE"},
+		{name: "sin dirección previa y solo el sur", policy: "keep_direction", south: south, want: "SUR"},
+
+		{name: "alterna del norte al sur", policy: "alternate", north: north, south: south, current: "NORTE", want: "SUR"},
+		{name: "alterna del sur al norte", policy: "alternate", north: north, south: south, current: "SUR", want: "NORTE"},
+		{name: "sigue si la otra cola está vacía", policy: "alternate", north: north, current: "NORTE", want: "NORTE"},
+		{name: "colas vacías al alternar", policy: "alternate", current: "SUR", want: ""},
+
+		{name: "el que llegó antes es del sur", policy: "fifo", north: north, south: south, current: "NORTE", want: "SUR"},
+		{name: "el que llegó antes es del norte", policy: "fifo", north: north, south: []Car{queued(3, "SUR", 15)}, current: "SUR", want: "NORTE"},
+		{name: "empate favorece al norte", policy: "fifo", north: north, south: []Car{queued(3, "SUR", 10)}, want: "NORTE"},
+		{name: "solo el norte", policy: "fifo", north: north, current: "SUR", want: "NORTE"},
+		{name: "solo el sur", policy: "fifo", south: south, current: "NORTE", want: "SUR"},
+		{name: "colas vacías en fifo", policy: "fifo", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy+"/"+tt.name, func(t *testing.T) {
+			policy, ok := schedulingPolicies[tt.policy]
+			if !ok {
+				t.Fatalf("política %q no registrada", tt.policy)
+			}
+			if got := policy(tt.north, tt.south, tt.current); got != tt.want {
+				t.Errorf("devolvió %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// La política fifo usa la llegada guardada en el registro, posterior al último descanso, antes que la de la copia encolada.
+func TestFifoPolicyPrefersRegistry(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	prevCars := allCars
+	allCars = map[int]Car{
+		1: {Car: api.Car{ID: 1, Direction: "NORTE"}, TimeEnteredQueue: base.Add(30 * time.Second)},
+	}
+	t.Cleanup(func() { allCars = prevCars })
+
+	north := []Car{{Car: api.Car{ID: 1, Direction: "NORTE"}, TimeEnteredQueue: base}}
+	south := []Car{{Car: api.Car{ID: 2, Direction: "SUR"}, TimeEnteredQueue: base.Add(10 * time.Second)}}
+	if got := fifoPolicy(north, south, ""); got != "SUR" {
+		t.Errorf("fifoPolicy devolvió %q, se esperaba SUR", got)
+	}
+}
+
+func TestPolicyNames(t *testing.T) {
+	want := []string{"alternate", "fifo", "keep_direction"}
+	got := policyNames()
+	if len(got) != len(want) {
+		t.Fatalf("policyNames() = %v, se esperaba %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("policyNames() = %v, se esperaba %v", got, want)
+		}
+	}
+	if _, ok := schedulingPolicies[defaultPolicy]; !ok {
+		t.Errorf("la política por defecto %q no está registrada", defaultPolicy)
+	}
+}
+
+// Los coches entran a las colas por requestCross con el puente cerrado y salen por dequeueNextCar, como en processQueue.
+func TestPolicyOrderWithRealQueues(t *testing.T) {
+	base := time.Now().Add(-time.Minute)
+	// Orden de llegada: N1, N2, S3, N4, S5.
+	arrivals := []Car{
+		{Car: api.Car{ID: 1, Direction: "NORTE"}, TimeEnteredQueue: base},
+		{Car: api.Car{ID: 2, Direction: "NORTE"}, TimeEnteredQueue: base.Add(1 * time.Second)},
+		{Car: api.Car{ID: 3, Direction: "SUR"}, TimeEnteredQueue: base.Add(2 * time.Second)},
+		{Car: api.Car{ID: 4, Direction: "NORTE"}, TimeEnteredQueue: base.Add(3 * time.Second)},
+		{Car: api.Car{ID: 5, Direction: "SUR"}, TimeEnteredQueue: base.Add(4 * time.Second)},
+	}
+	fill := func(policy, current string) {
+		t.Helper()
+		freshSimulation(t)
+		mutex.Lock()
+		bridgeClosed, activePolicy, currentDir = true, policy, current
+		for _, car := range arrivals {
+			allCars[car.ID] = car
+		}
+		mutex.Unlock()
+		for _, car := range arrivals {
+			requestCross(car)
+		}
+	}
+	drain := func() []int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		var order []int
+		for car := dequeueNextCar(); car != nil; car = dequeueNextCar() {
+			if car.Direction != currentDir {
+				t.Errorf("coche %d al %s con el puente hacia %s", car.ID, car.Direction, currentDir)
+			}
+			order = append(order, car.ID)
+		}
+		return order
+	}
+
+	fill("keep_direction", "")
+	if got := drain(); !reflect.DeepEqual(got, []int{1, 2, 4, 3, 5}) {
+		t.Errorf("keep_direction: orden %v", got)
+	}
+	fill("alternate", "")
+	if got := drain(); !reflect.DeepEqual(got, []int{1, 3, 2, 5, 4}) {
+		t.Errorf("alternate: orden %v", got)
+	}
+	// Alternar desde el norte da paso primero al sur.
+	fill("alternate", "NORTE")
+	if got := drain(); !reflect.DeepEqual(got, []int{3, 1, 5, 2, 4}) {
+		t.Errorf("alternate desde el norte: orden %v", got)
+	}
+	fill("fifo", "SUR")
+	if got := drain(); !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("fifo: orden %v", got)
+	}
+}
+
+// Un cambio de política por la API se aplica desde el siguiente coche, sin reordenar las colas.
+func TestPolicyChangeAppliesToNextCar(t *testing.T) {
+	freshSimulation(t)
+	prevKey := adminKey
+	adminKey = "k"
+	t.Cleanup(func() { adminKey = prevKey })
+
+	base := time.Now().Add(-time.Minute)
+	mutex.Lock()
+	bridgeClosed = true
+	for id, dir := range map[int]string{1: "NORTE", 2: "NORTE", 3: "NORTE", 4: "SUR"} {
+		allCars[id] = Car{Car: api.Car{ID: id, Direction: dir}, TimeEnteredQueue: base.Add(time.Duration(id) * time.Second)}
+	}
+	cars := []Car{allCars[1], allCars[2], allCars[3], allCars[4]}
+	mutex.Unlock()
+	for _, car := range cars {
+		requestCross(car)
+	}
+
+	next := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return dequeueNextCar().ID
+	}
+	if id := next(); id != 1 {
+		t.Fatalf("primer coche %d, se esperaba 1", id)
+	}
+	if id := next(); id != 2 {
+		t.Fatalf("keep_direction sigue al norte: coche %d, se esperaba 2", id)
+	}
+	if w := serveRoute(newRouter(false), "POST", "/api/admin/policy", "k", `{"policy":"alternate"}`); w.Code != http.StatusOK {
+		t.Fatalf("cambio de política: código %d %s", w.Code, w.Body)
+	}
+	if id := next(); id != 4 {
+		t.Errorf("tras pasar a alternate: coche %d, se esperaba 4", id)
+	}
+	if id := next(); id != 3 {
+		t.Errorf("último coche %d, se esperaba 3", id)
+	}
+}
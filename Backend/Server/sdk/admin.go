@@ -0,0 +1,65 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Devuelve una copia del cliente que envía la clave de administración en los métodos de administración.
+// Las acciones de vehículo no la envían: para actuar sobre un vehículo sin su token, se pasa la clave como token.
+func (c *Client) WithAdminKey(key string) *Client {
+	admin := *c
+	admin.adminKey = key
+	return &admin
+}
+
+// Reinicia la simulación: retira a todos los vehículos y pone a cero las estadísticas.
+// Requiere la clave de administración. Devuelve el mensaje del servidor.
+func (c *Client) Reset(ctx context.Context) (string, error) {
+	return c.action(ctx, "/api/admin/reset", c.adminKey)
+}
+
+// Devuelve la política de paso activa y las disponibles. Requiere la clave de administración.
+func (c *Client) Policy(ctx context.Context) (api.PolicyResponse, error) {
+	var resp api.PolicyResponse
+	err := c.do(ctx, http.MethodGet, "/api/admin/policy", c.adminKey, nil, nil, &resp)
+	return resp, err
+}
+
+// Cambia la política de paso. Requiere la clave de administración.
+func (c *Client) SetPolicy(ctx context.Context, policy string) (api.PolicyResponse, error) {
+	var resp api.PolicyResponse
+	err := c.do(ctx, http.MethodPost, "/api/admin/policy", c.adminKey, nil, api.PolicyRequest{Policy: policy}, &resp)
+	return resp, err
+}
+
+// Devuelve el volcado del estado completo de la simulación, tal como lo envía el servidor.
+// Requiere la clave de administración.
+func (c *Client) DumpState(ctx context.Context) (json.RawMessage, error) {
+	var state json.RawMessage
+	err := c.do(ctx, http.MethodGet, "/api/admin/state", c.adminKey, nil, nil, &state)
+	return state, err
+}
+
+// Devuelve una página de la auditoría de las acciones de administración; con desc, las más recientes
+// primero. Con page o pageSize en cero se usan los valores del servidor. Requiere la clave de administración.
+func (c *Client) Audit(ctx context.Context, page, pageSize int, desc bool) (api.AuditLogResponse, error) {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		query.Set("page_size", strconv.Itoa(pageSize))
+	}
+	if desc {
+		query.Set("order", "desc")
+	}
+	var resp api.AuditLogResponse
+	err := c.do(ctx, http.MethodGet, "/api/admin/audit", c.adminKey, query, nil, &resp)
+	return resp, err
+}
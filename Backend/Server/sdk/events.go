@@ -0,0 +1,57 @@
+package sdk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Error devuelto por Events cuando el servidor cierra el flujo sin que se cancele el contexto.
+var ErrStreamClosed = errors.New("el servidor cerró el flujo de eventos")
+
+// Recibe los eventos de /api/events y llama a fn con cada uno hasta que se corta el flujo o se
+// cancela el contexto. fn se llama desde la goroutine que invoca Events.
+func (c *Client) Events(ctx context.Context, fn func(api.Event)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/api/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.stream.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	// Cada evento SSE termina con una línea vacía; solo interesan sus líneas "data:".
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				var ev api.Event
+				if json.Unmarshal([]byte(data.String()), &ev) == nil {
+					fn(ev)
+				}
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return ErrStreamClosed
+}
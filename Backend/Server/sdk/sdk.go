@@ -0,0 +1,346 @@
+// Package sdk es el cliente de Go de la API REST del puente de una vía. Cada método corresponde a
+// un endpoint, recibe un contexto y devuelve los mismos tipos que usa el servidor (paquete api).
+// Las respuestas de error de la API ({"error": "..."}) llegan como *Error.
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Plazo por defecto de cada petición. El flujo de eventos no tiene plazo.
+const DefaultTimeout = 10 * time.Second
+
+// Cliente de la API REST. Es seguro usarlo desde varias goroutines.
+type Client struct {
+	base string
+	http *http.Client
+	// Sin plazo: el flujo de eventos permanece abierto indefinidamente.
+	stream *http.Client
+	// Clave de administración; solo la envían los métodos de administración.
+	adminKey string
+}
+
+// Error devuelto por la API: el código HTTP y el mensaje del campo "error" de la respuesta.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Crea un cliente para el servidor indicado ("host:puerto" o una URL completa).
+func New(server string) *Client {
+	return NewWithHTTPClient(server, &http.Client{Timeout: DefaultTimeout})
+}
+
+// Crea un cliente que usa hc para las peticiones; el flujo de eventos usa su mismo transporte sin plazo.
+func NewWithHTTPClient(server string, hc *http.Client) *Client {
+	if !strings.HasPrefix(server, "http://") && !strings.HasPrefix(server, "https://") {
+		server = "http://" + server
+	}
+	return &Client{
+		base:   strings.TrimSuffix(server, "/"),
+		http:   hc,
+		stream: &http.Client{Transport: hc.Transport},
+	}
+}
+
+// URL base del servidor, sin barra final.
+func (c *Client) BaseURL() string {
+	return c.base
+}
+
+// Registra un vehículo y lo pone en la cola de su dirección. La respuesta trae el token del vehículo,
+// que piden las acciones sobre él. Para volver a registrar un UUID ya registrado hay que pasar su
+// token; en el primer registro token va vacío.
+func (c *Client) Register(ctx context.Context, req api.RegisterRequest, token string) (api.RegisterResponse, error) {
+	var resp api.RegisterResponse
+	err := c.do(ctx, http.MethodPost, "/api/register", token, nil, req, &resp)
+	return resp, err
+}
+
+// Devuelve el estado actual del puente.
+func (c *Client) Status(ctx context.Context) (api.BridgeStatus, error) {
+	var status api.BridgeStatus
+	err := c.do(ctx, http.MethodGet, "/api/status", "", nil, nil, &status)
+	return status, err
+}
+
+// Devuelve los vehículos de ambas colas, en orden.
+func (c *Client) Queue(ctx context.Context) (api.QueueResponse, error) {
+	var q api.QueueResponse
+	err := c.do(ctx, http.MethodGet, "/api/queue", "", nil, nil, &q)
+	return q, err
+}
+
+// Devuelve un vehículo por su ID.
+func (c *Client) Vehicle(ctx context.Context, id int) (api.Car, error) {
+	var car api.Car
+	err := c.do(ctx, http.MethodGet, vehiclePath(id, ""), "", nil, nil, &car)
+	return car, err
+}
+
+// Filtros, orden y página del listado de vehículos. Los campos vacíos usan los valores del servidor.
+type VehicleQuery struct {
+	Status    string
+	Direction string
+	Looping   *bool
+	Synthetic *bool
+	// registered, crossings o avg_wait.
+	Sort     string
+	Desc     bool
+	Page     int
+	PageSize int
+}
+
+// Devuelve una página del listado de vehículos.
+func (c *Client) Vehicles(ctx context.Context, q VehicleQuery) (api.VehicleListResponse, error) {
+	query := url.Values{}
+	setIf(query, "status", q.Status)
+	setIf(query, "direction", q.Direction)
+	setIf(query, "sort", q.Sort)
+	if q.Looping != nil {
+		query.Set("looping", strconv.FormatBool(*q.Looping))
+	}
+	if q.Synthetic != nil {
+		query.Set("synthetic", strconv.FormatBool(*q.Synthetic))
+	}
+	if q.Desc {
+		query.Set("order", "desc")
+	}
+	if q.Page > 0 {
+		query.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(q.PageSize))
+	}
+	var list api.VehicleListResponse
+	err := c.do(ctx, http.MethodGet, "/api/vehicles", "", query, nil, &list)
+	return list, err
+}
+
+// Devuelve las estadísticas de un vehículo.
+func (c *Client) Stats(ctx context.Context, id int) (api.CarStatsResponse, error) {
+	var stats api.CarStatsResponse
+	err := c.do(ctx, http.MethodGet, vehiclePath(id, "stats"), "", nil, nil, &stats)
+	return stats, err
+}
+
+// Devuelve las estadísticas globales del puente.
+func (c *Client) BridgeStats(ctx context.Context) (api.BridgeStatsResponse, error) {
+	var stats api.BridgeStatsResponse
+	err := c.do(ctx, http.MethodGet, "/api/stats", "", nil, nil, &stats)
+	return stats, err
+}
+
+// Devuelve los indicadores de equidad del puente y de cada vehículo.
+func (c *Client) Fairness(ctx context.Context) (api.FairnessResponse, error) {
+	var fairness api.FairnessResponse
+	err := c.do(ctx, http.MethodGet, "/api/fairness", "", nil, nil, &fairness)
+	return fairness, err
+}
+
+// Devuelve la serie temporal de las colas entre from y to, agrupada en intervalos de tamaño step.
+// Un instante en cero o un paso en cero usan los valores del servidor: desde la muestra más antigua,
+// hasta ahora y con el intervalo de muestreo.
+func (c *Client) TimeSeries(ctx context.Context, from, to time.Time, step time.Duration) (api.TimeSeriesResponse, error) {
+	query := timeRange(from, to)
+	if step > 0 {
+		query.Set("step", step.String())
+	}
+	var series api.TimeSeriesResponse
+	err := c.do(ctx, http.MethodGet, "/api/timeseries", "", query, nil, &series)
+	return series, err
+}
+
+// Devuelve los cruces que terminaron entre from y to, en orden de salida, desde la exportación JSON.
+// Un instante en cero deja ese extremo abierto: desde el primer cruce o hasta ahora.
+func (c *Client) ExportCrossings(ctx context.Context, from, to time.Time) ([]api.CrossingRecord, error) {
+	var crossings []api.CrossingRecord
+	err := c.do(ctx, http.MethodGet, "/api/export/crossings.json", "", timeRange(from, to), nil, &crossings)
+	return crossings, err
+}
+
+// Devuelve el resumen de cada vehículo calculado con los cruces que terminaron entre from y to, desde
+// la exportación JSON. Un instante en cero deja ese extremo abierto.
+func (c *Client) ExportVehicles(ctx context.Context, from, to time.Time) ([]api.VehicleSummary, error) {
+	var vehicles []api.VehicleSummary
+	err := c.do(ctx, http.MethodGet, "/api/export/vehicles.json", "", timeRange(from, to), nil, &vehicles)
+	return vehicles, err
+}
+
+// Devuelve una página del historial de cruces de un vehículo. Requiere su token.
+// Con page o pageSize en cero se usan los valores del servidor.
+func (c *Client) History(ctx context.Context, id int, token string, page, pageSize int) (api.CrossingHistoryResponse, error) {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		query.Set("page_size", strconv.Itoa(pageSize))
+	}
+	var history api.CrossingHistoryResponse
+	err := c.do(ctx, http.MethodGet, vehiclePath(id, "history"), token, query, nil, &history)
+	return history, err
+}
+
+// Indica que el vehículo no vuelva a la cola tras su próximo cruce. Requiere su token.
+// Devuelve el mensaje del servidor.
+func (c *Client) Stop(ctx context.Context, id int, token string) (string, error) {
+	return c.action(ctx, vehiclePath(id, "stop"), token)
+}
+
+// Mantiene viva la sesión de un vehículo registrado por HTTP. Requiere su token.
+func (c *Client) Ping(ctx context.Context, id int, token string) error {
+	return c.do(ctx, http.MethodPost, vehiclePath(id, "ping"), token, nil, nil, nil)
+}
+
+// Avisa que un vehículo en modo arrendamiento salió del puente. Requiere su token.
+func (c *Client) Exit(ctx context.Context, id int, token string) error {
+	return c.do(ctx, http.MethodPost, vehiclePath(id, "exit"), token, nil, nil, nil)
+}
+
+// Retira de la cola a un vehículo en espera o en descanso. Requiere su token.
+// Devuelve el mensaje del servidor.
+func (c *Client) Cancel(ctx context.Context, id int, token string) (string, error) {
+	return c.action(ctx, vehiclePath(id, "cancel"), token)
+}
+
+// Elimina un vehículo del sistema. Requiere la clave de administración. Devuelve el mensaje del servidor.
+func (c *Client) Evict(ctx context.Context, id int) (string, error) {
+	return c.action(ctx, vehiclePath(id, "evict"), c.adminKey)
+}
+
+// Cierra el puente con el modo, el motivo y la reapertura prevista de req; el valor cero cierra en modo
+// drain hasta que se reabra. Requiere la clave de administración. Devuelve el mensaje del servidor.
+func (c *Client) CloseBridge(ctx context.Context, req api.CloseRequest) (string, error) {
+	var resp api.MessageResponse
+	err := c.do(ctx, http.MethodPost, "/api/bridge/close", c.adminKey, nil, req, &resp)
+	return resp.Message, err
+}
+
+// Reabre el puente. Requiere la clave de administración. Devuelve el mensaje del servidor.
+func (c *Client) OpenBridge(ctx context.Context) (string, error) {
+	return c.action(ctx, "/api/bridge/open", c.adminKey)
+}
+
+// Devuelve los cierres programados pendientes, por orden de inicio.
+func (c *Client) ClosureSchedule(ctx context.Context) ([]api.ScheduledClosure, error) {
+	var resp api.ClosureScheduleResponse
+	err := c.do(ctx, http.MethodGet, "/api/bridge/schedule", "", nil, nil, &resp)
+	return resp.Closures, err
+}
+
+// Programa un cierre del puente. Requiere la clave de administración.
+func (c *Client) ScheduleClosure(ctx context.Context, req api.ScheduleClosureRequest) (api.ScheduledClosure, error) {
+	var scheduled api.ScheduledClosure
+	err := c.do(ctx, http.MethodPost, "/api/bridge/schedule", c.adminKey, nil, req, &scheduled)
+	return scheduled, err
+}
+
+// Cancela un cierre programado que aún no empezó. Requiere la clave de administración.
+// Devuelve el mensaje del servidor.
+func (c *Client) CancelScheduledClosure(ctx context.Context, id int) (string, error) {
+	return c.action(ctx, "/api/bridge/schedule/"+strconv.Itoa(id)+"/cancel", c.adminKey)
+}
+
+// Ruta de un vehículo o de una de sus acciones.
+func vehiclePath(id int, action string) string {
+	path := "/api/vehicle/" + strconv.Itoa(id)
+	if action != "" {
+		path += "/" + action
+	}
+	return path
+}
+
+// Consulta con los parámetros from y to de los instantes que no están en cero.
+func timeRange(from, to time.Time) url.Values {
+	query := url.Values{}
+	if !from.IsZero() {
+		query.Set("from", from.Format(time.RFC3339Nano))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.Format(time.RFC3339Nano))
+	}
+	return query
+}
+
+// Añade el parámetro a la consulta si no está vacío.
+func setIf(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+// Envía una acción (POST) y devuelve el mensaje de confirmación del servidor.
+func (c *Client) action(ctx context.Context, path, token string) (string, error) {
+	var resp api.MessageResponse
+	err := c.do(ctx, http.MethodPost, path, token, nil, nil, &resp)
+	return resp.Message, err
+}
+
+// Realiza una petición con body codificado en JSON y decodifica la respuesta en out. Si token no está
+// vacío se envía como "Authorization: Bearer". Una respuesta fuera del rango 2xx se convierte en *Error.
+func (c *Client) do(ctx context.Context, method, path, token string, query url.Values, body, out interface{}) error {
+	u := c.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("respuesta inválida de %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// Convierte una respuesta de error de la API en *Error; si no trae mensaje usa el estado HTTP.
+func responseError(resp *http.Response) *Error {
+	var body api.ErrorResponse
+	if json.NewDecoder(resp.Body).Decode(&body) != nil || body.Error == "" {
+		body.Error = resp.Status
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: body.Error}
+}
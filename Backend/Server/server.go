@@ -2,62 +2,67 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
-	"fmt"
+	"flag"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"log"
+	"io"
 	"math/rand"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+// Los tipos que viajan por la API REST viven en el paquete api, compartido con el SDK de Go.
+type (
+	CarStats                = api.CarStats
+	vehicleDirectionTotals  = api.VehicleDirectionTotals
+	BridgeStatus            = api.BridgeStatus
+	CarStatsResponse        = api.CarStatsResponse
+	VehicleDirectionStats   = api.VehicleDirectionStats
+	TimeBreakdown           = api.TimeBreakdown
+	BridgeStatsResponse     = api.BridgeStatsResponse
+	DirectionStatsResponse  = api.DirectionStatsResponse
+	HistogramResponse       = api.HistogramResponse
+	HistogramBucket         = api.HistogramBucket
+	VehicleListItem         = api.VehicleListItem
+	VehicleListResponse     = api.VehicleListResponse
+	LeaderboardEntry        = api.LeaderboardEntry
+	LeaderboardResponse     = api.LeaderboardResponse
+	CrossingRecord          = api.CrossingRecord
+	CrossingHistoryResponse = api.CrossingHistoryResponse
+	BridgeClosure           = api.BridgeClosure
+	ScheduledClosure        = api.ScheduledClosure
+	bridgeEvent             = api.Event
+	TimeSeriesPoint         = api.TimeSeriesPoint
+	TimeSeriesResponse      = api.TimeSeriesResponse
+	DirectionFairness       = api.DirectionFairness
+	VehicleFairness         = api.VehicleFairness
+	FairnessResponse        = api.FairnessResponse
+	VehicleSummary          = api.VehicleSummary
 )
-// Estructura de un vehículo con sus propiedades y estado.
+
+// Vehículo tal como lo guarda el servidor: los datos que expone la API más los que solo usa la simulación.
 type Car struct {
-	ID               int       `json:"id"`
-	UUID             string    `json:"uuid"`
-	Direction        string    `json:"direction"`
-	Speed            int       `json:"speed"`
+	api.Car
 	Conn             net.Conn  `json:"-"`
-	Position         int       `json:"position"`
-	Status           string    `json:"status"`
-	IsLooping        bool      `json:"is_looping"`
-	Stats            CarStats  `json:"stats"`
 	LastSeen         time.Time `json:"-"`
-	CanRequeueAt     int64     `json:"can_requeue_at,omitempty"`
 	TimeEnteredQueue time.Time `json:"-"`
 	TimeStartedCross time.Time `json:"-"`
-}
-
-// Estructura para la respuesta de la API que muestra las estadísticas de un coche.
-type CarStatsResponse struct {
-	TotalCrossings       int     `json:"total_crossings"`
-	TotalTimeOnBridgeSec float64 `json:"total_time_on_bridge_sec"`
-	AvgCrossingTimeSec   float64 `json:"avg_crossing_time_sec"`
-	TotalWaitingTimeSec  float64 `json:"total_waiting_time_sec"`
-	AvgWaitingTimeSec    float64 `json:"avg_waiting_time_sec"`
-	TimeInBridgePercent  float64 `json:"time_in_bridge_percent"`
-}
-
-// Almacena los datos brutos de las estadísticas de un coche para cálculos internos.
-type CarStats struct {
-	TotalCrossings    int
-	TotalTimeOnBridge time.Duration
-	TotalWaitingTime  time.Duration
-	TimeRegistered    time.Time
-}
-
-// Representa el estado actual y en tiempo real del puente.
-type BridgeStatus struct {
-	Busy           bool   `json:"busy"`
-	CurrentDir     string `json:"current_dir"`
-	CurrentCarID   int    `json:"current_car_id"`
-	QueueNorthSize int    `json:"queue_north_size"`
-	QueueSouthSize int    `json:"queue_south_size"`
-	TrafficLight   string `json:"traffic_light"`
+	// Coches que ya esperaban en la cola de su dirección cuando llegó este.
+	QueueLengthAtArrival int `json:"-"`
+	// Momento en que un cierre inmediato lo sacó del puente. Las estadísticas del puente cuentan la espera de
+	// su siguiente paso desde aquí, porque la anterior ya quedó registrada con el paso evacuado.
+	EvacuatedAt time.Time `json:"-"`
 }
 
 // Variables globales para gestionar el estado de la simulación.
@@ -80,21 +85,76 @@ var (
 	clientRegistry = make(map[string]int)
 	// Mapa que almacena todos los coches para ser consultados por la API.
 	allCars        = make(map[int]Car)
+	// Sesiones TCP activas, indexadas por el ID del coche al que pertenecen.
+	tcpSessions    = make(map[int]*tcpSession)
+	// Arrendamiento vigente del puente, si el coche que cruza usa el modo arrendamiento.
+	activeLease    *bridgeLease
+	// Plazo máximo para que un coche en modo arrendamiento avise su salida.
+	leaseTimeout   time.Duration
+	// UUIDs de los clientes que dejaron vencer un arrendamiento.
+	faultyClients  = make(map[string]bool)
 )
 // Función principal que inicia los servidores y procesos en segundo plano.
 func main() {
+	// El subcomando replay reconstruye una sesión pasada sin iniciar la simulación.
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+
+	flag.DurationVar(&leaseTimeout, "lease-timeout", 30*time.Second, "plazo para que un vehículo en modo arrendamiento avise su salida")
+	flag.StringVar(&stateFile, "state-file", "puente_estado.json", "archivo donde se guarda el estado de la simulación (vacío para desactivar)")
+	flag.DurationVar(&snapshotInterval, "snapshot-interval", 15*time.Second, "cada cuánto se guarda una instantánea completa del estado")
+	flag.StringVar(&eventsFile, "events-file", "puente_eventos.jsonl", "registro de eventos del puente, solo de añadir (vacío para desactivar)")
+	flag.DurationVar(&sampleInterval, "sample-interval", time.Second, "cada cuánto se muestrean las colas para /api/timeseries")
+	flag.StringVar(&adminKey, "admin-key", os.Getenv("PUENTE_ADMIN_KEY"), "clave de la API de administración (o variable PUENTE_ADMIN_KEY; vacía para desactivarla)")
+	flag.StringVar(&auditFile, "audit-file", "puente_auditoria.jsonl", "registro de auditoría de las acciones de administración (vacío para guardarlo solo en memoria)")
+	flag.StringVar(&activePolicy, "policy", defaultPolicy, "política de paso inicial: keep_direction, alternate o fifo")
+	flag.Parse()
+
+	if _, ok := schedulingPolicies[activePolicy]; !ok {
+		log.Fatalf("Política de paso desconocida: %q. Disponibles: %v", activePolicy, policyNames())
+	}
+	if snapshotInterval <= 0 {
+		log.Fatalf("-snapshot-interval debe ser mayor que cero: %v", snapshotInterval)
+	}
+	if sampleInterval <= 0 {
+		log.Fatalf("-sample-interval debe ser mayor que cero: %v", sampleInterval)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "policy" {
+			policyFromFlag = true
+		}
+	})
+
+	// Recupera el estado anterior antes de aceptar conexiones.
+	startPersistence()
+	startEventLog()
+	startAuditLog()
+
 	go startTCPServer()
-	go startHTTPServer()
+	go startHTTPServer(false)
 	go cleanupInactiveCars()
+	go sampleTimeSeries()
+	go runClosureSchedule()
 
 	log.Println("Servidores iniciados. Presione Ctrl+C para salir.")
-	// Bloquea la rutina principal para mantener el programa activo.
-	select {}
+	// Bloquea la rutina principal hasta recibir la señal de salida.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	log.Println("Deteniendo el servidor...")
+	stopPersistence()
+	stopEventLog()
+	stopAuditLog()
 }
 
 // Inicializa y ejecuta el servidor TCP para aceptar conexiones de los vehículos.
 func startTCPServer() {
-	listener, err := net.Listen("tcp", ":8050")
+	// Los sondeos keep-alive permiten detectar clientes cuya máquina desapareció sin cerrar el socket.
+	lc := net.ListenConfig{KeepAlive: tcpKeepAlivePeriod}
+	listener, err := lc.Listen(context.Background(), "tcp", ":8050")
 	if err != nil {
 		log.Fatalf("Error al iniciar servidor TCP: %v", err)
 	}
@@ -112,29 +172,69 @@ func startTCPServer() {
 	}
 }
 
-// Configura las rutas de la API REST y pone en marcha el servidor HTTP.
-func startHTTPServer() {
+// Pone en marcha el servidor HTTP con las rutas de la API REST.
+func startHTTPServer(readOnly bool) {
+	log.Println("Servidor HTTP REST activo en :8080")
+	// Inicia el servidor HTTP y detiene el programa si ocurre un error fatal.
+	log.Fatal(http.ListenAndServe(":8080", newRouter(readOnly)))
+}
+
+// Configura las rutas de la API REST. Con readOnly solo sirve las consultas, el flujo de eventos y las métricas:
+// la reproducción de una sesión no acepta cambios.
+func newRouter(readOnly bool) http.Handler {
 	r := mux.NewRouter()
 
-	// Asigna las funciones manejadoras a cada ruta (endpoint) de la API.
+	// Asigna las funciones manejadoras a cada ruta (endpoint) de la API. Estas solo consultan el estado.
 	r.HandleFunc("/api/status", getStatusHandler).Methods("GET")
-	r.HandleFunc("/api/register", registerVehicleHandler).Methods("POST")
 	r.HandleFunc("/api/vehicle/{id}", getVehicleHandler).Methods("GET")
 	r.HandleFunc("/api/queue", getQueueHandler).Methods("GET")
-	r.HandleFunc("/api/vehicle/{id}/stop", stopVehicleLoopHandler).Methods("POST")
+	r.HandleFunc("/api/vehicles", listVehiclesHandler).Methods("GET")
+	r.HandleFunc("/api/leaderboard", leaderboardHandler).Methods("GET")
+	r.HandleFunc("/api/stats", getBridgeStatsHandler).Methods("GET")
+	r.HandleFunc("/api/timeseries", getTimeSeriesHandler).Methods("GET")
+	r.HandleFunc("/api/fairness", getFairnessHandler).Methods("GET")
+	r.HandleFunc("/api/export/crossings.csv", exportCrossingsHandler("csv")).Methods("GET")
+	r.HandleFunc("/api/export/crossings.json", exportCrossingsHandler("json")).Methods("GET")
+	r.HandleFunc("/api/export/vehicles.csv", exportVehiclesHandler("csv")).Methods("GET")
+	r.HandleFunc("/api/export/vehicles.json", exportVehiclesHandler("json")).Methods("GET")
 	r.HandleFunc("/api/vehicle/{id}/stats", getVehicleStatsHandler).Methods("GET")
-	r.HandleFunc("/api/vehicle/{id}/ping", pingHandler).Methods("POST")
+	r.HandleFunc("/api/bridge/schedule", getClosureScheduleHandler).Methods("GET")
+	r.HandleFunc("/api/events", streamEventsHandler).Methods("GET")
+	r.HandleFunc("/metrics", metricsHandler).Methods("GET")
+
+	// Rutas que modifican el estado o que requieren un token o la clave de administración.
+	if !readOnly {
+		r.HandleFunc("/api/register", registerVehicleHandler).Methods("POST")
+		r.HandleFunc("/api/vehicle/{id}/stop", requireVehicleToken(stopVehicleLoopHandler)).Methods("POST")
+		r.HandleFunc("/api/vehicle/{id}/ping", requireVehicleToken(pingHandler)).Methods("POST")
+		r.HandleFunc("/api/vehicle/{id}/exit", requireVehicleToken(exitVehicleHandler)).Methods("POST")
+		r.HandleFunc("/api/vehicle/{id}/history", requireVehicleToken(getVehicleHistoryHandler)).Methods("GET")
+		r.HandleFunc("/api/vehicle/{id}/cancel", requireVehicleToken(cancelVehicleHandler)).Methods("POST")
+		r.HandleFunc("/api/vehicle/{id}/evict", adminAction(evictVehicleHandler)).Methods("POST")
+		r.HandleFunc("/api/bridge/close", adminAction(closeBridgeHandler)).Methods("POST")
+		r.HandleFunc("/api/bridge/open", adminAction(openBridgeHandler)).Methods("POST")
+		r.HandleFunc("/api/bridge/schedule", adminAction(scheduleClosureHandler)).Methods("POST")
+		r.HandleFunc("/api/bridge/schedule/{id}/cancel", adminAction(cancelScheduledClosureHandler)).Methods("POST")
+		r.HandleFunc("/api/admin/reset", adminAction(resetSimulationHandler)).Methods("POST")
+		r.HandleFunc("/api/admin/policy", adminAction(getPolicyHandler)).Methods("GET")
+		r.HandleFunc("/api/admin/policy", adminAction(setPolicyHandler)).Methods("POST")
+		r.HandleFunc("/api/admin/state", adminAction(dumpStateHandler)).Methods("GET")
+		r.HandleFunc("/api/admin/audit", requireAdmin(getAuditLogHandler)).Methods("GET")
+	}
+
+	// Cuenta las peticiones por ruta y código de estado, incluidas las que no coinciden con ninguna ruta.
+	r.Use(metricsMiddleware)
+	r.NotFoundHandler = countRequests("unmatched", http.NotFoundHandler())
+	r.MethodNotAllowedHandler = countRequests("unmatched", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
 
 	// Configura los permisos de CORS (Cross-Origin Resource Sharing).
 	allowedOrigins := handlers.AllowedOrigins([]string{"*"})
 	allowedMethods := handlers.AllowedMethods([]string{"GET", "POST", "OPTIONS"})
-	allowedHeaders := handlers.AllowedHeaders([]string{"Content-Type", "X-Requested-With"})
+	allowedHeaders := handlers.AllowedHeaders([]string{"Content-Type", "X-Requested-With", "Authorization"})
 
-	corsRouter := handlers.CORS(allowedOrigins, allowedMethods, allowedHeaders)(r)
-
-	log.Println("Servidor HTTP REST activo en :8080")
-	// Inicia el servidor HTTP y detiene el programa si ocurre un error fatal.
-	log.Fatal(http.ListenAndServe(":8080", corsRouter))
+	return handlers.CORS(allowedOrigins, allowedMethods, allowedHeaders)(r)
 }
 
 // Manejador HTTP que recibe un 'ping' de un vehículo para actualizar su estado de actividad.
@@ -167,14 +267,23 @@ func cleanupInactiveCars() {
 		now := time.Now()
 		for id, car := range allCars {
 			// Comprueba si un coche (sin conexión TCP) ha estado inactivo por más de 15 segundos.
-			if car.Conn == nil && now.Sub(car.LastSeen) > 15*time.Second {
+			inactive := car.Conn == nil && now.Sub(car.LastSeen) > 15*time.Second
+			// Un coche TCP cuya sesión ya terminó no volverá a recibir mensajes, salvo que esté cruzando.
+			orphaned := car.Conn != nil && !hasLiveSession(id) && (currentCar == nil || currentCar.ID != id)
+			if inactive || orphaned {
 				log.Printf("[Limpiador] Auto %d (UUID: %s) inactivo. Eliminando del sistema.", id, car.UUID)
 
 				delete(allCars, id)
+				cleanupEvictions++
 
 				// Asegura que el coche también sea eliminado de las colas de espera.
 				queueNorth = removeCarFromSlice(queueNorth, id)
 				queueSouth = removeCarFromSlice(queueSouth, id)
+				noteQueueChange(now)
+				journalChange(id)
+				removed := carEvent(evRemoved, car)
+				removed.Reason = "inactive"
+				recordEvent(removed)
 			}
 		}
 		mutex.Unlock()
@@ -210,10 +319,13 @@ func getStatusHandler(w http.ResponseWriter, r *http.Request) {
 		QueueNorthSize: len(queueNorth),
 		QueueSouthSize: len(queueSouth),
 		TrafficLight:   "red",
+		Closed:         bridgeClosed,
+		Policy:         activePolicy,
+		Closure:        currentClosure(),
 	}
 
 	// Determina si el semáforo puede estar en verde para una nueva solicitud.
-	if !bridgeBusy || (currentCar != nil && currentCar.Direction == currentDir) {
+	if !bridgeClosed && (!bridgeBusy || (currentCar != nil && currentCar.Direction == currentDir)) {
 		status.TrafficLight = "green"
 	}
 
@@ -224,11 +336,7 @@ func getStatusHandler(w http.ResponseWriter, r *http.Request) {
 func registerVehicleHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Petición POST recibida en /api/register")
 
-	var req struct {
-		UUID      string `json:"uuid"`
-		Direction string `json:"direction"`
-		Speed     int    `json:"speed"`
-	}
+	var req api.RegisterRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Printf("Error decodificando JSON: %v", err)
@@ -241,29 +349,51 @@ func registerVehicleHandler(w http.ResponseWriter, r *http.Request) {
 	mutex.Lock()
 	// Verifica si el vehículo (por UUID) ya existe para asignarle el mismo ID.
 	assignedID, exists := clientRegistry[req.UUID]
+	// Los UUIDs son públicos en /api/vehicles: volver a registrar uno exige el token de su vehículo.
+	if exists && !canClaimVehicle(assignedID, bearerToken(r)) {
+		mutex.Unlock()
+		respondUnauthorized(w, "El UUID ya pertenece a un vehículo registrado. Envíe su token para volver a registrarlo")
+		return
+	}
 	if !exists {
 		carCounter++
 		assignedID = carCounter
 		clientRegistry[req.UUID] = assignedID
 	}
+	token, err := vehicleToken(assignedID)
+	if err != nil {
+		mutex.Unlock()
+		log.Printf("Error generando el token del auto %d: %v", assignedID, err)
+		respondWithError(w, http.StatusInternalServerError, "No se pudo generar el token del vehículo")
+		return
+	}
 
 	car := Car{
-		ID:        assignedID,
-		UUID:      req.UUID,
-		Direction: strings.ToUpper(req.Direction),
-		Speed:     req.Speed,
-		Status:    "waiting",
-		IsLooping: true,
+		Car: api.Car{
+			ID:        assignedID,
+			UUID:      req.UUID,
+			Direction: strings.ToUpper(req.Direction),
+			Speed:     req.Speed,
+			Status:    "waiting",
+			IsLooping: true,
+			LeaseMode: req.LeaseMode,
+			Synthetic: req.Synthetic,
+			Faulty:    faultyClients[req.UUID],
+			Stats: CarStats{
+				TotalCrossings: 0,
+				TimeRegistered: time.Now(),
+			},
+		},
 		Conn:      nil,
 		TimeEnteredQueue: time.Now(),
-		Stats: CarStats{
-			TotalCrossings: 0,
-			TimeRegistered: time.Now(),
-		},
 		LastSeen: time.Now(),
 	}
 
 	allCars[car.ID] = car
+	journalChange(car.ID)
+	registered := carEvent(evRegistered, car)
+	registered.Transport = "http"
+	recordEvent(registered)
 	mutex.Unlock()
 
 	// Inicia la solicitud de cruce en segundo plano para no bloquear la respuesta HTTP.
@@ -273,15 +403,13 @@ func registerVehicleHandler(w http.ResponseWriter, r *http.Request) {
 	defer mutex.Unlock()
 
 	trafficLight := "red"
-	if !bridgeBusy || currentDir == car.Direction {
+	if !bridgeClosed && (!bridgeBusy || currentDir == car.Direction) {
 		trafficLight = "green"
 	}
 
-	response := struct {
-		Car          Car          `json:"car"`
-		BridgeStatus BridgeStatus `json:"bridge_status"`
-	}{
-		Car: car,
+	response := api.RegisterResponse{
+		Car:   car.Car,
+		Token: token,
 		BridgeStatus: BridgeStatus{
 			Busy:       bridgeBusy,
 			CurrentDir: currentDir,
@@ -294,6 +422,9 @@ func registerVehicleHandler(w http.ResponseWriter, r *http.Request) {
 			TrafficLight:   trafficLight,
 			QueueNorthSize: len(queueNorth),
 			QueueSouthSize: len(queueSouth),
+			Closed:         bridgeClosed,
+			Policy:         activePolicy,
+			Closure:        currentClosure(),
 		},
 	}
 
@@ -318,7 +449,7 @@ func getVehicleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, car)
+	respondWithJSON(w, http.StatusOK, car.Car)
 	log.Printf("IDs registrados: %v", allCars)
 }
 
@@ -327,17 +458,23 @@ func getQueueHandler(w http.ResponseWriter, r *http.Request) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	response := struct {
-		North []Car `json:"north"`
-		South []Car `json:"south"`
-	}{
-		North: queueNorth,
-		South: queueSouth,
+	response := api.QueueResponse{
+		North: apiCars(queueNorth),
+		South: apiCars(queueSouth),
 	}
 
 	respondWithJSON(w, http.StatusOK, response)
 }
 
+// Devuelve los datos que la API expone de cada coche de una cola.
+func apiCars(queue []Car) []api.Car {
+	cars := make([]api.Car, len(queue))
+	for i, car := range queue {
+		cars[i] = car.Car
+	}
+	return cars
+}
+
 // Manejador HTTP para indicar que un vehículo no debe volver a ponerse en la cola después de cruzar.
 func stopVehicleLoopHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -350,6 +487,8 @@ func stopVehicleLoopHandler(w http.ResponseWriter, r *http.Request) {
 		// Actualiza el estado del coche para detener su ciclo de cruces.
 		car.IsLooping = false
 		allCars[id] = car
+		journalChange(id)
+		recordEvent(carEvent(evStopped, car))
 		log.Printf("Recibida orden de detener para el auto %d.", id)
 		respondWithJSON(w, http.StatusOK, map[string]string{"message": "El vehículo se detendrá después de su próximo cruce."})
 	} else {
@@ -376,29 +515,7 @@ func getVehicleStatsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Procesa las estadísticas crudas para generar una respuesta formateada.
-	stats := car.Stats
-	totalTime := time.Since(stats.TimeRegistered).Seconds()
-	timeOnBridge := stats.TotalTimeOnBridge.Seconds()
-	timeWaiting := stats.TotalWaitingTime.Seconds()
-
-	resp := CarStatsResponse{
-		TotalCrossings:       stats.TotalCrossings,
-		TotalTimeOnBridgeSec: timeOnBridge,
-		AvgCrossingTimeSec:   0,
-		TotalWaitingTimeSec:  timeWaiting,
-		AvgWaitingTimeSec:    0,
-		TimeInBridgePercent:  0,
-	}
-
-	if stats.TotalCrossings > 0 {
-		resp.AvgCrossingTimeSec = timeOnBridge / float64(stats.TotalCrossings)
-		resp.AvgWaitingTimeSec = timeWaiting / float64(stats.TotalCrossings)
-	}
-	if totalTime > 0 {
-		resp.TimeInBridgePercent = (timeOnBridge / totalTime) * 100
-	}
-
-	respondWithJSON(w, http.StatusOK, resp)
+	respondWithJSON(w, http.StatusOK, buildCarStats(car, time.Now()))
 }
 
 // Función auxiliar para codificar y enviar una respuesta JSON con un código de estado específico.
@@ -410,10 +527,15 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 
 // Función auxiliar que utiliza respondWithJSON para enviar un mensaje de error estandarizado.
 func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
+	respondWithJSON(w, code, api.ErrorResponse{Error: message})
 }
 // Maneja la conexión TCP inicial de un vehículo, lo registra y solicita su cruce.
 func handleClient(conn net.Conn) {
+	defer trackTCPConn()()
+
+	// Un cliente que no se identifica a tiempo no debe retener la conexión indefinidamente.
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+
 	reader := bufio.NewReader(conn)
 	line, err := reader.ReadString('\n')
 	if err != nil {
@@ -422,6 +544,12 @@ func handleClient(conn net.Conn) {
 		return
 	}
 
+	// Los clientes v2 abren la sesión con un mensaje JSON; el resto usa el formato CSV original.
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		handleSessionV2(conn, reader, strings.TrimSpace(line))
+		return
+	}
+
 	parts := strings.Split(strings.TrimSpace(line), ",")
 	if len(parts) != 3 {
 		log.Printf("Formato incorrecto. Se esperaban 3 partes (UUID,Dir,Vel), recibido: %s", line)
@@ -436,6 +564,13 @@ func handleClient(conn net.Conn) {
 	mutex.Lock()
 	// Verifica si el vehículo es nuevo para asignarle un ID numérico único.
 	assignedID, exists := clientRegistry[clientUUID]
+	// El protocolo v1 no puede enviar el token que protege a los vehículos registrados por HTTP o TCP v2.
+	if exists && !canClaimVehicle(assignedID, "") {
+		mutex.Unlock()
+		log.Printf("[Auto %d] Rechazado registro v1 de un UUID protegido por token o con una sesión abierta (UUID: %s).", assignedID, clientUUID)
+		conn.Close()
+		return
+	}
 	if !exists {
 		carCounter++
 		assignedID = carCounter
@@ -445,20 +580,39 @@ func handleClient(conn net.Conn) {
 	mutex.Unlock()
 
 	car := Car{
-		ID:        assignedID,
-		UUID:      clientUUID,
-		Direction: direction,
-		Speed:     speed,
+		Car: api.Car{
+			ID:        assignedID,
+			UUID:      clientUUID,
+			Direction: direction,
+			Speed:     speed,
+			Status:    "waiting",
+		},
 		Conn:      conn,
-		Status:    "waiting",
+		TimeEnteredQueue: time.Now(),
 	}
 
+	session := newTCPSession(conn, 1)
+	defer endSession(session)
+
 	mutex.Lock()
 	allCars[car.ID] = car
+	session.uuid = clientUUID
+	session.carID = car.ID
+	session.active = true
+	tcpSessions[car.ID] = session
+	journalChange(car.ID)
+	registered := carEvent(evRegistered, car)
+	registered.Transport = "tcp"
+	recordEvent(registered)
 	mutex.Unlock()
 
 	log.Printf("[Auto %d] solicita cruzar desde %s", car.ID, car.Direction)
 	requestCross(car)
+
+	// Los clientes v1 no envían nada más: la lectura solo termina cuando cierran la conexión
+	// o cuando el servidor la cierra al acabar el cruce.
+	conn.SetReadDeadline(time.Time{})
+	io.Copy(io.Discard, reader)
 }
 
 // Gestiona una solicitud de cruce: da paso si el puente está libre o encola el vehículo si está ocupado.
@@ -472,37 +626,67 @@ func requestCross(car Car) {
 		return
 	}
 
+	// Un coche cancelado por un operador no vuelve a la cola al terminar su descanso.
+	if c := allCars[car.ID]; c.Status == "cancelled" {
+		log.Printf("[Auto %d] Su solicitud fue cancelada. No vuelve a la cola.", car.ID)
+		return
+	}
+
 	if c, exists := allCars[car.ID]; exists {
 		c.Status = "waiting"
 		allCars[car.ID] = c
 	}
 
-	// Si el puente no está ocupado, el coche puede cruzar inmediatamente.
-	if !bridgeBusy {
+	// Si el puente no está ocupado ni cerrado, el coche puede cruzar inmediatamente.
+	if !bridgeBusy && !bridgeClosed {
 		bridgeBusy = true
 		currentDir = car.Direction
+		car.QueueLengthAtArrival = 0
 		currentCar = &car
 
 		if c, exists := allCars[car.ID]; exists {
 			c.Status = "crossing"
 			allCars[car.ID] = c
 		}
+		journalChange(car.ID)
 
-		go allowCross(car)
+		go allowCross(car, newEvacuation())
 		return
 	}
 
 	// Si el puente está ocupado, el coche se añade a la cola correspondiente.
+	position := 0
 	if car.Direction == "NORTE" {
+		car.QueueLengthAtArrival = len(queueNorth)
 		queueNorth = append(queueNorth, car)
+		position = len(queueNorth)
 	} else {
+		car.QueueLengthAtArrival = len(queueSouth)
 		queueSouth = append(queueSouth, car)
-	}
+		position = len(queueSouth)
+	}
+	noteQueueChange(time.Now())
+	notifyCar(car.ID, serverMessage{Type: msgQueued, ID: car.ID, Direction: car.Direction, Position: position})
+	journalChange(car.ID)
+	queued := carEvent(evQueued, car)
+	queued.Position = position
+	recordEvent(queued)
 }
 // Gestiona el proceso completo de un vehículo cruzando el puente: calcula la duración, simula el paso, actualiza estadísticas y decide si debe volver a la cola.
-func allowCross(car Car) {
+// Un cierre inmediato del puente cierra evacuate y el coche vuelve al frente de su cola.
+func allowCross(car Car, evacuate <-chan struct{}) {
+	// El arrendamiento se registra antes del permiso para aceptar un aviso de salida inmediato.
+	var lease *bridgeLease
+	if car.LeaseMode {
+		lease = startLease(car)
+	}
+
+	// Un cliente TCP que no recibe el permiso ya no está ahí: el puente pasa al siguiente coche.
 	if car.Conn != nil {
-		fmt.Fprintf(car.Conn, "Auto %d, permiso concedido para cruzar\n", car.ID)
+		if err := grantTCPClient(car); err != nil {
+			abandonCrossing(car, err)
+			return
+		}
 	} else {
 		log.Printf("[Auto %d, Cliente HTTP] Permiso concedido para cruzar.", car.ID)
 	}
@@ -510,16 +694,46 @@ func allowCross(car Car) {
 	// Registra el momento exacto en que comienza el cruce.
 	startTime := time.Now()
 
+	// Tiempo que el coche pasó en la cola, informado al cliente al terminar el cruce.
+	var waitTime time.Duration
+	// Adelantamientos sufridos durante esa espera, que se guardan con el cruce.
+	var overtaken int
+
 	mutex.Lock()
+	// Un reinicio de la simulación pudo liberar el puente antes de que empezara el cruce.
+	if !holdsBridge(car.ID) {
+		if lease != nil && activeLease == lease {
+			activeLease = nil
+		}
+		mutex.Unlock()
+		return
+	}
+	// Espera que se suma a las estadísticas del puente. Tras una evacuación solo cuenta la parte nueva.
+	var bridgeWait time.Duration
 	if c, exists := allCars[car.ID]; exists {
 		c.Status = "crossing"
 
 		// Actualiza las estadísticas de tiempo de espera del coche.
-		waitTime := startTime.Sub(c.TimeEnteredQueue)
-		c.Stats.TotalWaitingTime += waitTime
+		waitTime = startTime.Sub(c.TimeEnteredQueue)
+		noteVehicleGrant(&c.Stats, car.Direction, waitTime)
+		bridgeWait = waitTime
+		if c.EvacuatedAt.IsZero() {
+			noteOvertakes(c)
+		} else {
+			// La espera anterior y los coches adelantados ya se contaron con el paso evacuado.
+			bridgeWait = startTime.Sub(c.EvacuatedAt)
+			c.EvacuatedAt = time.Time{}
+		}
 		c.TimeStartedCross = startTime
+		overtaken = c.OvertakenInQueue
+		c.OvertakenInQueue = 0
 		allCars[car.ID] = c
 	}
+	journalChange(car.ID)
+	granted := carEvent(evGranted, car)
+	granted.WaitSec = waitTime.Seconds()
+	recordEvent(granted)
+	noteBridgeGrant(car.Direction, bridgeWait, startTime)
 	mutex.Unlock()
 
 	// Calcula la duración del cruce basándose en la velocidad del coche.
@@ -530,32 +744,79 @@ func allowCross(car Car) {
 	tiempoCruceServidor := int(tiempoCruceFloat)
 	tiempoCruceServidor += rand.Intn(3) - 1
 
-	log.Printf("[Auto %d, Vel: %d] Cruzando el puente... (duración calculada: %d segundos)", car.ID, car.Speed, tiempoCruceServidor)
-	// Simula el tiempo que el coche tarda en cruzar el puente.
-	time.Sleep(time.Duration(tiempoCruceServidor) * time.Second)
+	if lease != nil {
+		// En modo arrendamiento el puente sigue ocupado hasta que el cliente avisa su salida.
+		if !waitForExit(lease, evacuate) {
+			if evacuated(evacuate) {
+				evacuateCrossing(car, grantRecord{wait: waitTime, overtaken: overtaken})
+				return
+			}
+			revokeLease(car)
+			return
+		}
+	} else {
+		log.Printf("[Auto %d, Vel: %d] Cruzando el puente... (duración calculada: %d segundos)", car.ID, car.Speed, tiempoCruceServidor)
+		// Simula el tiempo que el coche tarda en cruzar el puente.
+		if !simulateCrossing(car.ID, time.Duration(tiempoCruceServidor)*time.Second, evacuate) {
+			evacuateCrossing(car, grantRecord{wait: waitTime, overtaken: overtaken})
+			return
+		}
+	}
 
 	endTime := time.Now()
 
 	mutex.Lock()
 	defer mutex.Unlock()
 
+	// El cruce terminó a la vez que un reinicio de la simulación, que ya liberó el puente.
+	if !holdsBridge(car.ID) {
+		return
+	}
+
+	// Informa al cliente TCP de los mismos tiempos que se suman a sus estadísticas.
+	notifyCar(car.ID, serverMessage{
+		Type:        msgFinished,
+		ID:          car.ID,
+		Direction:   car.Direction,
+		DurationSec: endTime.Sub(startTime).Seconds(),
+		WaitSec:     waitTime.Seconds(),
+	})
+	finishSessionCrossing(car.ID)
+	finished := carEvent(evFinished, car)
+	finished.DurationSec = endTime.Sub(startTime).Seconds()
+	finished.WaitSec = waitTime.Seconds()
+	recordEvent(finished)
+	noteBridgeExit(car.Direction, endTime.Sub(startTime), endTime)
+	recordCrossing(CrossingRecord{
+		CarID:                car.ID,
+		Direction:            car.Direction,
+		QueuedAt:             startTime.Add(-waitTime),
+		GrantedAt:            startTime,
+		ExitedAt:             endTime,
+		WaitSec:              waitTime.Seconds(),
+		DurationSec:          endTime.Sub(startTime).Seconds(),
+		QueueLengthAtArrival: car.QueueLengthAtArrival,
+		TimesOvertaken:       overtaken,
+	})
+
 	// Vuelve a verificar si el coche aún existe, ya que pudo ser eliminado mientras cruzaba.
 	c, exists := allCars[car.ID]
 	if !exists {
 		log.Printf("[Auto %d] Terminó de cruzar pero ya fue eliminado del registro.", car.ID)
 		bridgeBusy = false
 		currentCar = nil
+		journalChange()
 		go processQueue()
 		return
 	}
 
-	c.Stats.TotalCrossings++
-
-	// Calcula y registra el tiempo real que el coche estuvo en el puente.
-	cruceReal := endTime.Sub(c.TimeStartedCross)
-	c.Stats.TotalTimeOnBridge += cruceReal
+	// Calcula y registra el tiempo real que el coche estuvo en el puente. Se mide desde el inicio de este cruce:
+	// si el mismo UUID se volvió a registrar mientras cruzaba, el registro nuevo no tiene hora de inicio.
+	cruceReal := endTime.Sub(startTime)
+	noteVehicleCrossing(&c.Stats, car.Direction, cruceReal)
 
 	c.Status = "finished"
+	c.LeaseExpiresAt = 0
 
 	// Invierte la dirección del coche para su próximo viaje si está en modo bucle.
 	if c.Direction == "NORTE" {
@@ -572,74 +833,162 @@ func allowCross(car Car) {
 
 	// Si el coche debe seguir cruzando, lo reencola después de un descanso.
 	if c.IsLooping {
-		go func(carToRequeue Car) {
-			tiempoEspera := rand.Intn(13) + 6
-			requeueTime := time.Now().Add(time.Duration(tiempoEspera) * time.Second)
-
-			mutex.Lock()
-			if car, exists := allCars[carToRequeue.ID]; exists {
-				car.CanRequeueAt = requeueTime.Unix()
-				allCars[car.ID] = car
-			}
-			mutex.Unlock()
-
-			log.Printf("[Auto %d] Descansando por %d segundos. Podrá volver a la cola a las %s.", carToRequeue.ID, tiempoEspera, requeueTime.Format("15:04:05"))
-			// Pausa para simular el descanso del coche antes de volver a la cola.
-			time.Sleep(time.Duration(tiempoEspera) * time.Second)
-
-			mutex.Lock()
-			if car, exists := allCars[carToRequeue.ID]; exists {
-				car.CanRequeueAt = 0
-				car.TimeEnteredQueue = time.Now()
-				allCars[car.ID] = car
-			}
-			mutex.Unlock()
-
-			// Vuelve a solicitar el cruce para iniciar el ciclo de nuevo.
-			requestCross(carToRequeue)
-
-		}(c)
+		tiempoEspera := rand.Intn(13) + 6
+		go scheduleRequeue(c, time.Duration(tiempoEspera)*time.Second)
 	} else {
 		log.Printf("[Auto %d] Ha terminado su ciclo. Eliminando del sistema.", c.ID)
 		// Si no está en bucle, se elimina permanentemente del sistema.
 		delete(allCars, c.ID)
+		removed := carEvent(evRemoved, c)
+		removed.Reason = "cycle_complete"
+		recordEvent(removed)
 	}
+	journalChange(c.ID)
 }
-// Revisa las colas y gestiona el paso del siguiente vehículo según la prioridad de dirección.
-func processQueue() {
+
+// Mantiene al coche en descanso durante el tiempo indicado y luego lo vuelve a poner en la cola.
+func scheduleRequeue(carToRequeue Car, tiempoEspera time.Duration) {
+	restStart := time.Now()
+	requeueTime := restStart.Add(tiempoEspera)
+
+	mutex.Lock()
+	if car, exists := allCars[carToRequeue.ID]; exists {
+		car.CanRequeueAt = requeueTime.Unix()
+		allCars[car.ID] = car
+		journalChange(car.ID)
+		resting := carEvent(evResting, car)
+		resting.RestSec = tiempoEspera.Seconds()
+		recordEvent(resting)
+	}
+	mutex.Unlock()
+
+	log.Printf("[Auto %d] Descansando por %v. Podrá volver a la cola a las %s.", carToRequeue.ID, tiempoEspera.Round(time.Second), requeueTime.Format("15:04:05"))
+	// Pausa para simular el descanso del coche antes de volver a la cola.
+	time.Sleep(tiempoEspera)
+
+	mutex.Lock()
+	if car, exists := allCars[carToRequeue.ID]; exists {
+		car.CanRequeueAt = 0
+		car.TimeEnteredQueue = time.Now()
+		noteVehicleRest(&car.Stats, car.TimeEnteredQueue.Sub(restStart))
+		allCars[car.ID] = car
+	}
+	mutex.Unlock()
+
+	// Vuelve a solicitar el cruce para iniciar el ciclo de nuevo.
+	requestCross(carToRequeue)
+}
+// Registra como abandonado el cruce de un coche cuyo cliente no recibió el permiso y libera el puente.
+func abandonCrossing(car Car, err error) {
+	log.Printf("[Auto %d] No se pudo entregar el permiso de cruce: %v. Cruce abandonado.", car.ID, err)
+
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	// Evita procesar la cola si el puente ya está ocupado, previniendo condiciones de carrera.
-	if bridgeBusy {
+	// Un reinicio de la simulación ya liberó el puente y cerró la sesión del coche.
+	if !holdsBridge(car.ID) {
+		if activeLease != nil && activeLease.carID == car.ID {
+			activeLease = nil
+		}
 		return
 	}
+	if c, exists := allCars[car.ID]; exists {
+		c.Status = "abandoned"
+		c.LeaseExpiresAt = 0
+		c.Stats.AbandonedCrossings++
+		allCars[car.ID] = c
+	}
+	bridgeStats.AbandonedCrossings++
+	if activeLease != nil && activeLease.carID == car.ID {
+		activeLease = nil
+	}
+	// Sin sesión, el limpiador retirará el coche en su próxima pasada.
+	if session, ok := tcpSessions[car.ID]; ok {
+		delete(tcpSessions, car.ID)
+		session.close()
+	}
 
-	var nextCar *Car
+	bridgeBusy = false
+	currentCar = nil
+	journalChange(car.ID)
+	abandoned := carEvent(evAbandoned, car)
+	abandoned.Reason = err.Error()
+	recordEvent(abandoned)
+	go processQueue()
+}
 
-	// Lógica de prioridad: primero coches en la dirección actual, luego cambia de sentido.
-	if currentDir == "NORTE" && len(queueNorth) > 0 {
-		nextCar = &queueNorth[0]
-		queueNorth = queueNorth[1:]
-	} else if currentDir == "SUR" && len(queueSouth) > 0 {
-		nextCar = &queueSouth[0]
-		queueSouth = queueSouth[1:]
-	} else if len(queueNorth) > 0 {
+// Indica si el coche sigue teniendo el puente: un reinicio de la simulación lo libera a mitad de cruce.
+// Debe llamarse con el mutex bloqueado.
+func holdsBridge(carID int) bool {
+	return currentCar != nil && currentCar.ID == carID
+}
+
+// Espera la duración del cruce informando periódicamente del avance al cliente TCP del coche.
+// Devuelve false si el cruce se interrumpió para evacuar el puente o por un reinicio de la simulación.
+func simulateCrossing(carID int, duration time.Duration, evacuate <-chan struct{}) bool {
+	start := time.Now()
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case <-evacuate:
+			return false
+		case now := <-ticker.C:
+			elapsed := now.Sub(start)
+			mutex.Lock()
+			notifyCar(carID, serverMessage{
+				Type:        msgProgress,
+				ID:          carID,
+				ElapsedSec:  elapsed.Seconds(),
+				DurationSec: duration.Seconds(),
+				Percent:     min(elapsed.Seconds()/duration.Seconds()*100, 100),
+			})
+			mutex.Unlock()
+		}
+	}
+}
+// Saca de su cola al siguiente coche y fija con él la dirección del puente; nil si las colas están vacías.
+// La política activa elige la dirección; dentro de cada cola se respeta el orden de llegada. Debe llamarse con el mutex bloqueado.
+func dequeueNextCar() *Car {
+	var nextCar *Car
+	switch schedulingPolicies[activePolicy](queueNorth, queueSouth, currentDir) {
+	case "NORTE":
 		nextCar = &queueNorth[0]
 		queueNorth = queueNorth[1:]
 		currentDir = "NORTE"
-	} else if len(queueSouth) > 0 {
+	case "SUR":
 		nextCar = &queueSouth[0]
 		queueSouth = queueSouth[1:]
 		currentDir = "SUR"
 	}
+	return nextCar
+}
+
+// Revisa las colas y gestiona el paso del siguiente vehículo según la política de paso activa.
+func processQueue() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// Evita procesar la cola si el puente ya está ocupado, previniendo condiciones de carrera.
+	// Con el puente cerrado los coches siguen en cola hasta que se reabra.
+	if bridgeBusy || bridgeClosed {
+		return
+	}
 
-	if nextCar != nil {
+	if nextCar := dequeueNextCar(); nextCar != nil {
 		bridgeBusy = true
 		currentCar = nextCar
 
 		// Inicia el cruce en una goroutine para no mantener el mutex bloqueado.
-		go allowCross(*nextCar)
+		go allowCross(*nextCar, newEvacuation())
+		noteQueueChange(time.Now())
+		notifyQueuePositions()
+		journalChange(nextCar.ID)
 	} else {
 		log.Println("Todas las colas están vacías. El puente ahora está libre.")
 	}
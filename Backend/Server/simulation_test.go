@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Deja la simulación vacía, con el puente abierto y libre, para que la prueba empiece desde cero. Un cruce
+// que quedara en curso de una prueba anterior se despierta y, como ya no tiene el puente, termina sin tocar nada.
+func freshSimulation(t *testing.T) {
+	t.Helper()
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if evacuationCh != nil {
+		close(evacuationCh)
+		evacuationCh = nil
+	}
+	allCars = make(map[int]Car)
+	queueNorth, queueSouth = nil, nil
+	clientRegistry = make(map[string]int)
+	vehicleTokens = make(map[int]string)
+	tcpSessions = make(map[int]*tcpSession)
+	faultyClients = make(map[string]bool)
+	crossingHistory = make(map[int][]CrossingRecord)
+	carCounter = 0
+	bridgeBusy, currentCar, currentDir = false, nil, ""
+	bridgeClosed, bridgeClosure = false, BridgeClosure{}
+	closureSchedule, scheduleCounter = nil, 0
+	activeLease = nil
+	activePolicy = defaultPolicy
+	bridgeStats = newBridgeTotals(time.Now())
+	timeSeries.reset()
+	leaseTimeout = time.Minute
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Capacidad del búfer circular de muestras: seis horas con el intervalo por defecto.
+const maxTimeSeriesSamples = 6 * 60 * 60
+
+// Número máximo de puntos por respuesta; si el paso pedido daría más, se ensancha.
+const maxTimeSeriesPoints = 2000
+
+// Estado de las colas y del puente en un instante.
+type timeSample struct {
+	Time       time.Time
+	QueueNorth int
+	QueueSouth int
+	Busy       bool
+	Direction  string
+}
+
+// Búfer circular de muestras, de la más antigua a la más reciente a partir de start.
+type sampleRing struct {
+	samples []timeSample
+	start   int
+	count   int
+}
+
+// Variables del muestreo de la serie temporal.
+var (
+	// Cada cuánto se toma una muestra de las colas y del puente.
+	sampleInterval time.Duration
+	// Muestras tomadas. Protegidas por el mutex global.
+	timeSeries = sampleRing{samples: make([]timeSample, maxTimeSeriesSamples)}
+)
+
+// Añade una muestra, descartando la más antigua si el búfer está lleno.
+func (ring *sampleRing) add(s timeSample) {
+	if ring.count < len(ring.samples) {
+		ring.samples[(ring.start+ring.count)%len(ring.samples)] = s
+		ring.count++
+		return
+	}
+	ring.samples[ring.start] = s
+	ring.start = (ring.start + 1) % len(ring.samples)
+}
+
+// Descarta todas las muestras.
+func (ring *sampleRing) reset() {
+	ring.start, ring.count = 0, 0
+}
+
+// Devuelve la muestra i-ésima en orden cronológico.
+func (ring *sampleRing) at(i int) timeSample {
+	return ring.samples[(ring.start+i)%len(ring.samples)]
+}
+
+// Proceso en segundo plano que toma una muestra de las colas y del puente a intervalos fijos.
+func sampleTimeSeries() {
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		mutex.Lock()
+		timeSeries.add(timeSample{
+			Time:       now,
+			QueueNorth: len(queueNorth),
+			QueueSouth: len(queueSouth),
+			Busy:       bridgeBusy,
+			Direction:  currentDir,
+		})
+		mutex.Unlock()
+	}
+}
+
+// Agrupa las muestras entre from y to en intervalos de tamaño step. Debe llamarse con el mutex bloqueado.
+func downsample(from, to time.Time, step time.Duration) []TimeSeriesPoint {
+	points := []TimeSeriesPoint{}
+	var current *TimeSeriesPoint
+	var busy int
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		n := float64(current.Samples)
+		current.QueueNorthAvg /= n
+		current.QueueSouthAvg /= n
+		current.BusyRatio = float64(busy) / n
+		points = append(points, *current)
+	}
+
+	for i := 0; i < timeSeries.count; i++ {
+		s := timeSeries.at(i)
+		if s.Time.Before(from) || s.Time.After(to) {
+			continue
+		}
+		// Los intervalos se alinean a múltiplos de step para que dos consultas seguidas coincidan.
+		bucketStart := s.Time.Truncate(step)
+		if current == nil || !current.Time.Equal(bucketStart) {
+			flush()
+			current = &TimeSeriesPoint{Time: bucketStart}
+			busy = 0
+		}
+		current.Samples++
+		current.QueueNorthAvg += float64(s.QueueNorth)
+		current.QueueSouthAvg += float64(s.QueueSouth)
+		current.QueueNorthMax = max(current.QueueNorthMax, s.QueueNorth)
+		current.QueueSouthMax = max(current.QueueSouthMax, s.QueueSouth)
+		if s.Busy {
+			busy++
+		}
+		current.Direction = s.Direction
+	}
+	flush()
+	return points
+}
+
+// Manejador HTTP que devuelve la serie temporal de las colas, opcionalmente acotada y agrupada.
+func getTimeSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	now := time.Now()
+
+	to, err := parseTimeParam(query.Get("to"), now)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Parámetro 'to' inválido: use segundos Unix o RFC 3339")
+		return
+	}
+	step, err := parseStepParam(query.Get("step"), sampleInterval)
+	if err != nil || step <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Parámetro 'step' inválido: use una duración como 10s o un número de segundos")
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	oldest := now
+	if timeSeries.count > 0 {
+		oldest = timeSeries.at(0).Time
+	}
+	from, err := parseTimeParam(query.Get("from"), oldest)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Parámetro 'from' inválido: use segundos Unix o RFC 3339")
+		return
+	}
+	if to.Before(from) {
+		respondWithError(w, http.StatusBadRequest, "'from' debe ser anterior a 'to'")
+		return
+	}
+
+	// Evita respuestas enormes cuando se pide un rango largo con un paso pequeño.
+	if minStep := to.Sub(from) / maxTimeSeriesPoints; step < minStep {
+		step = minStep
+	}
+
+	respondWithJSON(w, http.StatusOK, TimeSeriesResponse{
+		From:        from,
+		To:          to,
+		StepSec:     step.Seconds(),
+		IntervalSec: sampleInterval.Seconds(),
+		Points:      downsample(from, to, step),
+	})
+}
+
+// Interpreta un instante en segundos Unix o en RFC 3339; si está vacío devuelve def.
+func parseTimeParam(v string, def time.Time) (time.Time, error) {
+	if v == "" {
+		return def, nil
+	}
+	if sec, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Unix(0, 0).Add(secondsToDuration(sec)), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// Interpreta un paso como duración de Go (10s, 1m) o como número de segundos; si está vacío devuelve def.
+func parseStepParam(v string, def time.Duration) (time.Duration, error) {
+	if v == "" {
+		return def, nil
+	}
+	if sec, err := strconv.ParseFloat(v, 64); err == nil {
+		return secondsToDuration(sec), nil
+	}
+	return time.ParseDuration(v)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tamaño de página por defecto y máximo del listado de vehículos.
+const (
+	defaultVehiclePageSize = 50
+	maxVehiclePageSize     = 500
+)
+
+// Número de puestos por defecto y máximo de la clasificación.
+const (
+	defaultLeaderboardSize = 10
+	maxLeaderboardSize     = 100
+)
+
+// Filtros y orden del listado de vehículos.
+type vehicleQuery struct {
+	status    string
+	direction string
+	// Los filtros booleanos son nil cuando no se piden.
+	looping   *bool
+	synthetic *bool
+	// Campo de orden: registered, crossings o avg_wait.
+	sortBy string
+	desc   bool
+	// Si es verdadero, solo se incluyen los vehículos con al menos un cruce.
+	crossedOnly bool
+}
+
+// Lee los filtros comunes (status, direction, looping, synthetic) de la URL.
+func parseVehicleFilters(r *http.Request) (vehicleQuery, error) {
+	query := r.URL.Query()
+	q := vehicleQuery{
+		status:    strings.ToLower(query.Get("status")),
+		direction: strings.ToUpper(query.Get("direction")),
+	}
+	if q.direction != "" && q.direction != "NORTE" && q.direction != "SUR" {
+		return q, fmt.Errorf("Dirección inválida: %s", q.direction)
+	}
+	var err error
+	if q.looping, err = parseOptionalBool(query.Get("looping")); err != nil {
+		return q, fmt.Errorf("'looping' debe ser true o false")
+	}
+	if q.synthetic, err = parseOptionalBool(query.Get("synthetic")); err != nil {
+		return q, fmt.Errorf("'synthetic' debe ser true o false")
+	}
+	return q, nil
+}
+
+// Interpreta un booleano opcional de la URL; nil si está vacío.
+func parseOptionalBool(v string) (*bool, error) {
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Indica si el vehículo cumple los filtros de la consulta.
+func (q vehicleQuery) matches(car Car, item VehicleListItem) bool {
+	switch {
+	case q.status != "" && car.Status != q.status:
+		return false
+	case q.direction != "" && car.Direction != q.direction:
+		return false
+	case q.looping != nil && car.IsLooping != *q.looping:
+		return false
+	case q.synthetic != nil && car.Synthetic != *q.synthetic:
+		return false
+	case q.crossedOnly && item.Crossings == 0:
+		return false
+	}
+	return true
+}
+
+// Devuelve, filtrados y ordenados, los vehículos registrados. Debe llamarse con el mutex bloqueado.
+func listVehicles(q vehicleQuery, now time.Time) []VehicleListItem {
+	vehicles := []VehicleListItem{}
+	for _, car := range allCars {
+		stats := buildCarStats(car, now)
+		item := VehicleListItem{
+			ID:           car.ID,
+			UUID:         car.UUID,
+			Direction:    car.Direction,
+			Speed:        car.Speed,
+			Status:       car.Status,
+			IsLooping:    car.IsLooping,
+			Synthetic:    car.Synthetic,
+			RegisteredAt: car.Stats.TimeRegistered,
+			Crossings:    stats.TotalCrossings,
+			AvgWaitSec:   stats.AvgWaitingTimeSec,
+			MaxWaitSec:   stats.MaxWaitingTimeSec,
+		}
+		if q.matches(car, item) {
+			vehicles = append(vehicles, item)
+		}
+	}
+
+	// Los empates se resuelven siempre por ID ascendente, también en orden descendente.
+	sort.Slice(vehicles, func(i, j int) bool {
+		a, b := vehicles[i], vehicles[j]
+		var c int
+		switch q.sortBy {
+		case "crossings":
+			c = cmp.Compare(a.Crossings, b.Crossings)
+		case "avg_wait":
+			c = cmp.Compare(a.AvgWaitSec, b.AvgWaitSec)
+		default:
+			c = a.RegisteredAt.Compare(b.RegisteredAt)
+		}
+		if q.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+		return a.ID < b.ID
+	})
+	return vehicles
+}
+
+// Manejador HTTP que lista los vehículos registrados con filtros, orden y paginación.
+func listVehiclesHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseVehicleFilters(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	switch q.sortBy = query.Get("sort"); q.sortBy {
+	case "", "registered", "crossings", "avg_wait":
+	default:
+		respondWithError(w, http.StatusBadRequest, "Orden inválido: use registered, crossings o avg_wait")
+		return
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.desc = true
+	default:
+		respondWithError(w, http.StatusBadRequest, "'order' debe ser asc o desc")
+		return
+	}
+
+	page, pageSize, ok := parsePagination(r, defaultVehiclePageSize, maxVehiclePageSize)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Parámetros de paginación inválidos")
+		return
+	}
+
+	mutex.Lock()
+	vehicles := listVehicles(q, time.Now())
+	mutex.Unlock()
+
+	start := min((page-1)*pageSize, len(vehicles))
+	end := min(start+pageSize, len(vehicles))
+	respondWithJSON(w, http.StatusOK, VehicleListResponse{
+		Total:    len(vehicles),
+		Page:     page,
+		PageSize: pageSize,
+		Vehicles: vehicles[start:end],
+	})
+}
+
+// Manejador HTTP que devuelve la clasificación de vehículos por cruces o por espera media.
+func leaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseVehicleFilters(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	by := r.URL.Query().Get("by")
+	switch by {
+	case "", "crossings":
+		// Más cruces primero.
+		by, q.sortBy, q.desc = "crossings", "crossings", true
+	case "avg_wait":
+		// Menor espera media primero, entre los que ya cruzaron.
+		q.sortBy, q.crossedOnly = "avg_wait", true
+	default:
+		respondWithError(w, http.StatusBadRequest, "Clasificación inválida: use crossings o avg_wait")
+		return
+	}
+
+	limit := defaultLeaderboardSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "'limit' debe ser un entero positivo")
+			return
+		}
+		limit = min(n, maxLeaderboardSize)
+	}
+
+	mutex.Lock()
+	vehicles := listVehicles(q, time.Now())
+	mutex.Unlock()
+
+	resp := LeaderboardResponse{By: by, Entries: []LeaderboardEntry{}}
+	for i, v := range vehicles[:min(limit, len(vehicles))] {
+		resp.Entries = append(resp.Entries, LeaderboardEntry{Rank: i + 1, Vehicle: v})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Moringa07/Puente-De-Una-Via/Backend/Server/api"
+)
+
+// Pide una página de /api/vehicles y devuelve el código y la respuesta decodificada.
+func listVehiclesPage(t *testing.T, query string) (int, VehicleListResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	listVehiclesHandler(w, httptest.NewRequest("GET", "/api/vehicles?"+query, nil))
+	var resp VehicleListResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("respuesta no es JSON: %s", w.Body)
+		}
+	}
+	return w.Code, resp
+}
+
+// IDs de los vehículos de una página, en orden.
+func listedIDs(resp VehicleListResponse) []int {
+	ids := make([]int, len(resp.Vehicles))
+	for i, v := range resp.Vehicles {
+		ids[i] = v.ID
+	}
+	return ids
+}
+
+func TestListVehiclesPages(t *testing.T) {
+	freshSimulation(t)
+	// Doce coches registrados uno por minuto; los pares van al sur y cruzaron id%4 veces.
+	start := time.Now().Add(-time.Hour)
+	mutex.Lock()
+	for id := 1; id <= 12; id++ {
+		car := Car{Car: api.Car{ID: id, UUID: "v" + strconv.Itoa(id), Direction: "NORTE", Status: "waiting"}}
+		if id%2 == 0 {
+			car.Direction = "SUR"
+			car.Stats.TotalCrossings = id % 4
+		}
+		car.Stats.TimeRegistered = start.Add(time.Duration(id) * time.Minute)
+		allCars[id] = car
+	}
+	mutex.Unlock()
+
+	code, page := listVehiclesPage(t, "page=2&page_size=5")
+	if code != http.StatusOK || page.Total != 12 || page.Page != 2 || page.PageSize != 5 {
+		t.Fatalf("página 2: código %d, %+v", code, page)
+	}
+	if got := listedIDs(page); len(got) != 5 || got[0] != 6 || got[4] != 10 {
+		t.Errorf("página 2 = %v, se esperaban los coches 6 a 10", got)
+	}
+
+	_, page = listVehiclesPage(t, "page=3&page_size=5")
+	if got := listedIDs(page); len(got) != 2 || got[1] != 12 {
+		t.Errorf("última página = %v, se esperaban los coches 11 y 12", got)
+	}
+	_, page = listVehiclesPage(t, "page=4&page_size=5")
+	if page.Total != 12 || page.Vehicles == nil || len(page.Vehicles) != 0 {
+		t.Errorf("página más allá del final = %+v, se esperaba una lista vacía", page)
+	}
+	_, page = listVehiclesPage(t, "page_size=100000")
+	if page.PageSize != maxVehiclePageSize || len(page.Vehicles) != 12 {
+		t.Errorf("tamaño pedido por encima del máximo: page_size=%d, %d coches", page.PageSize, len(page.Vehicles))
+	}
+
+	// Los filtros se aplican antes de paginar: el total cuenta solo los coches del sur.
+	_, page = listVehiclesPage(t, "direction=sur&sort=crossings&order=desc&page_size=4")
+	if got := listedIDs(page); page.Total != 6 || len(got) != 4 || got[0] != 2 || got[1] != 6 || got[2] != 10 {
+		t.Errorf("sur por cruces = %v (total %d), se esperaba 2, 6, 10 y luego los que no cruzaron", got, page.Total)
+	}
+
+	for _, query := range []string{"page=" + strconv.Itoa(maxPage+1), "page=0", "sort=speed", "direction=ESTE"} {
+		if code, _ := listVehiclesPage(t, query); code != http.StatusBadRequest {
+			t.Errorf("%s: código %d, se esperaba 400", query, code)
+		}
+	}
+}
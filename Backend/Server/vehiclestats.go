@@ -0,0 +1,115 @@
+package main
+
+import (
+	"time"
+)
+
+// Devuelve los acumulados del vehículo en una dirección.
+func vehicleDirection(s *CarStats, dir string) *vehicleDirectionTotals {
+	if dir == "SUR" {
+		return &s.South
+	}
+	return &s.North
+}
+
+// Registra que el vehículo recibió paso tras esperar wait.
+func noteVehicleGrant(s *CarStats, dir string, wait time.Duration) {
+	s.TotalWaitingTime += wait
+	totals := vehicleDirection(s, dir)
+	totals.Grants++
+	totals.TotalWait += wait
+	totals.MaxWait = max(totals.MaxWait, wait)
+}
+
+// Deshace un paso registrado con noteVehicleGrant cuyo cruce no llegó a contar. La espera máxima se conserva.
+func undoVehicleGrant(s *CarStats, dir string, wait time.Duration) {
+	s.TotalWaitingTime -= wait
+	totals := vehicleDirection(s, dir)
+	totals.Grants--
+	totals.TotalWait -= wait
+}
+
+// Registra que el vehículo terminó un cruce de la duración indicada.
+func noteVehicleCrossing(s *CarStats, dir string, crossed time.Duration) {
+	s.TotalCrossings++
+	s.TotalTimeOnBridge += crossed
+	totals := vehicleDirection(s, dir)
+	totals.Crossings++
+	totals.TotalCrossed += crossed
+}
+
+// Registra un descanso completo entre dos cruces.
+func noteVehicleRest(s *CarStats, rest time.Duration) {
+	s.Rests++
+	s.TotalRestTime += rest
+}
+
+// Prepara las estadísticas de una dirección para la API.
+func vehicleDirectionResponse(t vehicleDirectionTotals) VehicleDirectionStats {
+	stats := VehicleDirectionStats{
+		Grants:           t.Grants,
+		Crossings:        t.Crossings,
+		TotalWaitSec:     t.TotalWait.Seconds(),
+		MaxWaitSec:       t.MaxWait.Seconds(),
+		TotalCrossingSec: t.TotalCrossed.Seconds(),
+	}
+	if t.Grants > 0 {
+		stats.AvgWaitSec = t.TotalWait.Seconds() / float64(t.Grants)
+	}
+	if t.Crossings > 0 {
+		stats.AvgCrossingTimeSec = t.TotalCrossed.Seconds() / float64(t.Crossings)
+	}
+	return stats
+}
+
+// Calcula las estadísticas de un vehículo en el instante indicado.
+func buildCarStats(car Car, now time.Time) CarStatsResponse {
+	stats := car.Stats
+	totalTime := now.Sub(stats.TimeRegistered).Seconds()
+	timeOnBridge := stats.TotalTimeOnBridge.Seconds()
+	timeWaiting := stats.TotalWaitingTime.Seconds()
+	timeResting := stats.TotalRestTime.Seconds()
+	grants := stats.North.Grants + stats.South.Grants
+
+	resp := CarStatsResponse{
+		TotalCrossings:       stats.TotalCrossings,
+		TotalTimeOnBridgeSec: timeOnBridge,
+		TotalWaitingTimeSec:  timeWaiting,
+		MaxWaitingTimeSec:    max(stats.North.MaxWait, stats.South.MaxWait).Seconds(),
+		TotalRestTimeSec:     timeResting,
+		Rests:                stats.Rests,
+		AbandonedCrossings:   stats.AbandonedCrossings,
+		RevokedLeases:        stats.RevokedLeases,
+		Directions: map[string]VehicleDirectionStats{
+			"NORTE": vehicleDirectionResponse(stats.North),
+			"SUR":   vehicleDirectionResponse(stats.South),
+		},
+	}
+
+	if stats.TotalCrossings > 0 {
+		resp.AvgCrossingTimeSec = timeOnBridge / float64(stats.TotalCrossings)
+	}
+	// Como en el puente, la espera se promedia por permiso concedido y no por cruce terminado.
+	if grants > 0 {
+		resp.AvgWaitingTimeSec = timeWaiting / float64(grants)
+	}
+	if stats.Rests > 0 {
+		resp.AvgRestTimeSec = timeResting / float64(stats.Rests)
+	}
+
+	breakdown := TimeBreakdown{
+		QueuedSec:   timeWaiting,
+		CrossingSec: timeOnBridge,
+		RestingSec:  timeResting,
+		OtherSec:    max(totalTime-timeWaiting-timeOnBridge-timeResting, 0),
+	}
+	if totalTime > 0 {
+		resp.TimeInBridgePercent = (timeOnBridge / totalTime) * 100
+		breakdown.QueuedPercent = timeWaiting / totalTime * 100
+		breakdown.CrossingPercent = timeOnBridge / totalTime * 100
+		breakdown.RestingPercent = timeResting / totalTime * 100
+		breakdown.OtherPercent = breakdown.OtherSec / totalTime * 100
+	}
+	resp.TimeBreakdown = breakdown
+	return resp
+}
@@ -0,0 +1,47 @@
+package main
+
+import "sync"
+
+// Cola sin límite entre quien produce registros con el mutex global bloqueado y la rutina que los escribe en
+// disco. push nunca bloquea ni descarta: un disco lento no frena la simulación y no se pierde ningún registro.
+type writeQueue[T any] struct {
+	mu     sync.Mutex
+	ready  *sync.Cond
+	items  []T
+	closed bool
+}
+
+// Crea una cola vacía.
+func newWriteQueue[T any]() *writeQueue[T] {
+	q := &writeQueue[T]{}
+	q.ready = sync.NewCond(&q.mu)
+	return q
+}
+
+// Añade un registro al final de la cola.
+func (q *writeQueue[T]) push(item T) {
+	q.mu.Lock()
+	q.items = append(q.items, item)
+	q.mu.Unlock()
+	q.ready.Signal()
+}
+
+// Cierra la cola. La rutina de escritura recibe lo pendiente y luego termina.
+func (q *writeQueue[T]) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.ready.Signal()
+}
+
+// Espera hasta que haya registros y los devuelve todos, en orden. ok es false cuando la cola se cerró y ya
+// no queda nada por escribir.
+func (q *writeQueue[T]) take() (items []T, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.ready.Wait()
+	}
+	items, q.items = q.items, nil
+	return items, len(items) > 0 || !q.closed
+}
@@ -18,6 +18,9 @@ const translate = (key) => {
   return translations[key?.toLowerCase()] || key?.toUpperCase() || 'N/A';
 };
 
+// Indica si la página muestra una sesión reproducida por el servidor (go run . replay -live).
+const isReplay = new URLSearchParams(window.location.search).has('replay');
+
 // Componente principal que renderiza y gestiona la simulación.
 export default function Simulation() {
 
@@ -29,6 +32,7 @@ export default function Simulation() {
   const [carStats, setCarStats] = useState(null); // Almacena las estadísticas del vehículo para mostrarlas en el modal.
   const [crossingTime, setCrossingTime] = useState(0);// Guarda el tiempo restante de cruce del vehículo del usuario.
   const [restingTime, setRestingTime] = useState(0);// Guarda el tiempo restante de descanso antes de volver a la cola.
+  const [leaderboard, setLeaderboard] = useState([]);// Guarda los primeros puestos de la clasificación por cruces.
 
   // Referencias para almacenar los IDs de los intervalos y temporizadores.
   const pollingIntervalRef = useRef(null);
@@ -82,7 +86,10 @@ export default function Simulation() {
     const sendHeartbeat = async () => {
       if (carConfig?.id) {
         try {
-          const res = await fetch(`/api/vehicle/${carConfig.id}/ping`, { method: 'POST' });
+          const res = await fetch(`/api/vehicle/${carConfig.id}/ping`, {
+            method: 'POST',
+            headers: { Authorization: `Bearer ${carConfig.token}` },
+          });
           if (!res.ok) {
             console.warn("Sesión expirada. Deteniendo comunicación.");
             cleanupIntervals();
@@ -97,11 +104,30 @@ export default function Simulation() {
     if (carConfig?.id) {
       pollingIntervalRef.current = setInterval(fetchSimulationState, 1000);
       heartbeatIntervalRef.current = setInterval(sendHeartbeat, 5000);
+    } else if (carConfig?.replay) {
+      // En una repetición no hay coche propio: solo se consulta el estado.
+      pollingIntervalRef.current = setInterval(fetchSimulationState, 1000);
     }
 
     // Función de limpieza que se ejecuta al desmontar el componente.
     return cleanupIntervals;
-  }, [carConfig?.id]);
+  }, [carConfig?.id, carConfig?.replay]);
+
+  // Efecto que consulta periódicamente la clasificación de vehículos por número de cruces.
+  useEffect(() => {
+    const fetchLeaderboard = async () => {
+      try {
+        const res = await fetch('/api/leaderboard?by=crossings&limit=5');
+        if (res.ok) setLeaderboard((await res.json()).entries ?? []);
+      } catch (error) {
+        console.error("Error al obtener la clasificación:", error);
+      }
+    };
+
+    fetchLeaderboard();
+    const interval = setInterval(fetchLeaderboard, 5000);
+    return () => clearInterval(interval);
+  }, []);
 
   // Efecto para gestionar los temporizadores de cuenta regresiva (cruce y descanso).
   useEffect(() => {
@@ -140,6 +166,12 @@ export default function Simulation() {
 
   // Efecto de inicialización. Se ejecuta una vez para registrar el vehículo.
   useEffect(() => {
+    // En modo repetición no se registra ningún vehículo.
+    if (isReplay) {
+      setCarConfig({ replay: true, spriteType: 1 });
+      return;
+    }
+
     const params = new URLSearchParams(window.location.search);
     let dir = params.get('dir');
     let vel = parseInt(params.get('vel'), 10);
@@ -157,20 +189,22 @@ export default function Simulation() {
   // Registra el vehículo en el servidor y opcionalmente genera más vehículos.
   const handleModalSubmit = async ({ direccion, velocidad }) => {
     try {
+      // Las pestañas abiertas automáticamente se registran como vehículos sintéticos.
+      const params = new URLSearchParams(window.location.search);
+      const isFromURL = params.has('dir') && params.has('vel');
+
       const response = await fetch('/api/register', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ uuid: uuidv4(), direction: direccion.toUpperCase(), speed: velocidad }),
+        body: JSON.stringify({ uuid: uuidv4(), direction: direccion.toUpperCase(), speed: velocidad, synthetic: isFromURL }),
       });
       if (!response.ok) throw new Error('Error al registrar');
 
       const data = await response.json();
-      setCarConfig({ ...data.car, spriteType: (data.car.id % 4) + 1 });
+      // El token identifica a esta pestaña como dueña del vehículo ante el servidor.
+      setCarConfig({ ...data.car, token: data.token, spriteType: (data.car.id % 4) + 1 });
       setBridgeStatus(data.bridge_status);
 
-      const params = new URLSearchParams(window.location.search);
-      const isFromURL = params.has('dir') && params.has('vel');
-
       // Si es el primer vehículo, crea vehículos adicionales en nuevas pestañas.
       if (!isFromURL) {
         const numExtraCars = Math.floor(Math.random() * 5);
@@ -195,7 +229,10 @@ export default function Simulation() {
   const handleStopLoop = async () => {
     if (!carConfig || isLoopingStopped) return;
     try {
-      await fetch(`/api/vehicle/${carConfig.id}/stop`, { method: 'POST' });
+      await fetch(`/api/vehicle/${carConfig.id}/stop`, {
+        method: 'POST',
+        headers: { Authorization: `Bearer ${carConfig.token}` },
+      });
       setIsLoopingStopped(true);
     } catch (error) {
       console.error("Error al detener:", error);
@@ -222,7 +259,7 @@ export default function Simulation() {
       <header className="header">
         <div className="header-left">
           {/* Muestra el botón de detener o el de ver estadísticas */}
-          {!isLoopingStopped ? (
+          {isReplay ? null : !isLoopingStopped ? (
             <button onClick={handleStopLoop} className="stop-car-button">Terminar Simulación</button>
           ) : (
             <button onClick={handleShowStats} className="stats-button">Ver Estadísticas</button>
@@ -250,7 +287,7 @@ export default function Simulation() {
           <aside className="left-panel">
             <div className="panel-box">
               <h3>Tu Vehículo</h3>
-              <p><strong>ID:</strong> {carConfig.id}</p>
+              <p><strong>ID:</strong> {isReplay ? 'Repetición' : carConfig.id}</p>
               <div className="placeholder-sprite">
                 <img src={`/car${carConfig.spriteType}.png`} alt="Icono de tu auto" className="car-icon-sprite" />
               </div>
@@ -287,6 +324,19 @@ export default function Simulation() {
                 </span>
               </p>
             </div>
+
+            <div className="panel-box">
+              <h3>Clasificación</h3>
+              {leaderboard.length === 0 ? (
+                <p>Sin cruces todavía</p>
+              ) : (
+                leaderboard.map(({ rank, vehicle }) => (
+                  <p key={vehicle.id}>
+                    <strong>#{rank}</strong> Auto {vehicle.id}{vehicle.id === carConfig?.id ? ' (tú)' : ''}: {vehicle.crossings} cruces
+                  </p>
+                ))
+              )}
+            </div>
           </aside>
 
           {/* Panel central que contiene la visualización gráfica de la simulación */}
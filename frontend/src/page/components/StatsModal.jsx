@@ -49,6 +49,34 @@ export const StatsModal = ({ stats, carId, onClose }) => {
               {stats?.time_in_bridge_percent?.toFixed(2) ?? '...'}%
             </span>
           </div>
+
+          <div className="stat-item">
+            <span className="stat-label">Espera Máxima</span>
+            <span className="stat-value">
+              {stats?.max_waiting_time_sec?.toFixed(2) ?? '...'} s
+            </span>
+          </div>
+
+          <div className="stat-item">
+            <span className="stat-label">Tiempo Total Descansando</span>
+            <span className="stat-value">
+              {stats?.total_rest_time_sec?.toFixed(2) ?? '...'} s
+            </span>
+          </div>
+
+          <div className="stat-item">
+            <span className="stat-label">Promedio Espera Norte</span>
+            <span className="stat-value">
+              {stats?.directions?.NORTE?.avg_wait_sec?.toFixed(2) ?? '...'} s
+            </span>
+          </div>
+
+          <div className="stat-item">
+            <span className="stat-label">Promedio Espera Sur</span>
+            <span className="stat-value">
+              {stats?.directions?.SUR?.avg_wait_sec?.toFixed(2) ?? '...'} s
+            </span>
+          </div>
         </div>
 
         <button onClick={onClose} className="submit-button">